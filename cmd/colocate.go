@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/colocate"
+	"github.com/dtrejod/airgradient-exporter/internal/ilog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	referenceFlag = "reference"
+	candidateFlag = "candidate"
+	intervalFlag  = "interval"
+	durationFlag  = "duration"
+	recordFlag    = "record"
+)
+
+var (
+	colocateReference  string
+	colocateCandidates []string
+	colocateInterval   time.Duration
+	colocateDuration   time.Duration
+	colocateRecord     string
+)
+
+var colocateCmd = &cobra.Command{
+	Use:   "colocate",
+	Short: "Compute calibration coefficients of devices co-located with a reference device",
+	Long: `Polls a reference device and candidate devices placed next to it on aligned
+timestamps, then fits a regression per channel of each candidate against the
+reference. Prints the fits and a calibrations config snippet ready to paste.
+Interrupting the command fits the readings recorded so far.`,
+	RunE: colocateRunFunc,
+}
+
+func colocateRunFunc(cmd *cobra.Command, args []string) error {
+	if colocateReference == "" || len(colocateCandidates) == 0 {
+		return fmt.Errorf("'--reference' and at least one '--candidate' are required")
+	}
+	if colocateInterval <= 0 {
+		return fmt.Errorf("'--interval' must be positive")
+	}
+	session, err := colocate.NewSession(colocateReference, colocateCandidates)
+	if err != nil {
+		return err
+	}
+
+	var record *csv.Writer
+	if colocateRecord != "" {
+		f, err := os.Create(colocateRecord)
+		if err != nil {
+			return fmt.Errorf("could not create record file: %w", err)
+		}
+		defer f.Close()
+		record = csv.NewWriter(f)
+		defer record.Flush()
+		if err := record.Write([]string{"time", "role", "serialno", "temperature", "humidity", "co2", "pm25"}); err != nil {
+			return err
+		}
+	}
+
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	deadline := time.Now().Add(colocateDuration)
+	ilog.FromContext(ctx).Info("Starting co-location.",
+		zap.String("reference", colocateReference), zap.Strings("candidates", colocateCandidates),
+		zap.Duration("interval", colocateInterval), zap.Duration("duration", colocateDuration))
+
+	// Readings are taken on multiples of the interval so that every device is
+	// read at the same time.
+	for {
+		next := time.Now().Truncate(colocateInterval).Add(colocateInterval)
+		if next.After(deadline) {
+			break
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-runCtx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if runCtx.Err() != nil {
+			break
+		}
+
+		o, err := session.Sample(runCtx, next)
+		if err != nil {
+			ilog.FromContext(ctx).Warn("Skipped co-location reading.", zap.Time("time", next), zap.Error(err))
+			continue
+		}
+		if record != nil {
+			if err := writeObservation(record, o); err != nil {
+				return fmt.Errorf("could not record readings: %w", err)
+			}
+		}
+	}
+
+	results := session.Results()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERIALNO\tCHANNEL\tSAMPLES\tR2\tBIAS\tINTERCEPT\tSLOPE")
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s\t%s\t%d\t-\t-\t-\t-\n", r.SerialNo, r.Channel, r.Samples)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.4f\t%.4g\t%.6g\t%.6g\n", r.SerialNo, r.Channel, r.Samples, r.Fit.R2, r.Bias, r.Fit.Intercept, r.Fit.Slope)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Print(colocate.ConfigSnippet(results, time.Now().Format(time.DateOnly)))
+	return nil
+}
+
+func writeObservation(w *csv.Writer, o colocate.Observation) error {
+	t := o.T.UTC().Format(time.RFC3339)
+	rows := [][]string{readingRecord(t, "reference", o.Reference)}
+	for _, c := range o.Candidates {
+		if c != nil {
+			rows = append(rows, readingRecord(t, "candidate", c))
+		}
+	}
+	return w.WriteAll(rows)
+}
+
+func readingRecord(t, role string, r *colocate.Reading) []string {
+	row := []string{t, role, r.SerialNo}
+	for _, ch := range colocate.Channels {
+		row = append(row, strconv.FormatFloat(ch.Value(r), 'g', -1, 64))
+	}
+	return row
+}
+
+func init() {
+	colocateCmd.Flags().StringVar(&colocateReference, referenceFlag, "", "AirGradient local-server endpoint of the reference device.")
+	if err := viper.BindPFlag(referenceFlag, colocateCmd.Flags().Lookup(referenceFlag)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindEnv(referenceFlag, "COLOCATE_REFERENCE"); err != nil {
+		panic(err)
+	}
+	colocateReference = viper.GetString(referenceFlag)
+
+	colocateCmd.Flags().StringArrayVar(&colocateCandidates, candidateFlag, nil, "AirGradient local-server endpoint of a device to calibrate. May be repeated.")
+	if err := viper.BindPFlag(candidateFlag, colocateCmd.Flags().Lookup(candidateFlag)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindEnv(candidateFlag, "COLOCATE_CANDIDATES"); err != nil {
+		panic(err)
+	}
+	colocateCandidates = viper.GetStringSlice(candidateFlag)
+
+	colocateCmd.Flags().DurationVar(&colocateInterval, intervalFlag, time.Minute, "Interval between readings.")
+	if err := viper.BindPFlag(intervalFlag, colocateCmd.Flags().Lookup(intervalFlag)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindEnv(intervalFlag, "COLOCATE_INTERVAL"); err != nil {
+		panic(err)
+	}
+	colocateInterval = viper.GetDuration(intervalFlag)
+
+	colocateCmd.Flags().DurationVar(&colocateDuration, durationFlag, 7*24*time.Hour, "Duration of the co-location.")
+	if err := viper.BindPFlag(durationFlag, colocateCmd.Flags().Lookup(durationFlag)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindEnv(durationFlag, "COLOCATE_DURATION"); err != nil {
+		panic(err)
+	}
+	colocateDuration = viper.GetDuration(durationFlag)
+
+	colocateCmd.Flags().StringVar(&colocateRecord, recordFlag, "", "Path of a CSV file to record the readings to.")
+	if err := viper.BindPFlag(recordFlag, colocateCmd.Flags().Lookup(recordFlag)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindEnv(recordFlag, "COLOCATE_RECORD"); err != nil {
+		panic(err)
+	}
+	colocateRecord = viper.GetString(recordFlag)
+
+	rootCmd.AddCommand(colocateCmd)
+}
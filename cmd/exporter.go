@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/dtrejod/airgradient-exporter/internal/collector"
+	"github.com/dtrejod/airgradient-exporter/internal/config"
 	"github.com/dtrejod/airgradient-exporter/internal/ilog"
 	"github.com/dtrejod/airgradient-exporter/version"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -17,11 +21,17 @@ import (
 const (
 	listenAddrFlag = "listen-address"
 	endpointFlag   = "endpoint"
+	configFlag     = "config"
+
+	// shutdownTimeout is how long in-flight requests are given to complete
+	// on shutdown.
+	shutdownTimeout = 10 * time.Second
 )
 
 var (
 	listenAddr string
 	endpoint   string
+	configFile string
 )
 
 var exporterCmd = &cobra.Command{
@@ -33,27 +43,54 @@ var exporterCmd = &cobra.Command{
 func exporterRunFunc(cmd *cobra.Command, args []string) {
 	ilog.FromContext(ctx).Info("Starting airgradient-exporter...", zap.String("version", version.Version()))
 
-	if endpoint == "" {
-		ilog.FromContext(ctx).Fatal("Missing required '--endpoint' arguement.")
+	cfg := &config.Config{}
+	if configFile != "" {
+		var err error
+		cfg, err = config.Load(configFile)
+		if err != nil {
+			ilog.FromContext(ctx).Fatal("Failed to load config file.", zap.String("path", configFile), zap.Error(err))
+			os.Exit(1)
+		}
+	}
+	if endpoint != "" {
+		cfg.Devices = append(cfg.Devices, config.Device{Endpoint: endpoint})
+	}
+	if len(cfg.Devices) == 0 {
+		ilog.FromContext(ctx).Fatal("Missing required '--endpoint' or '--config' arguement.")
 		os.Exit(1)
 	}
 
-	airgradientCollector, err := collector.NewAirGradient(ctx, endpoint)
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	metricsHandler, err := collector.NewHandler(runCtx, *cfg)
 	if err != nil {
 		ilog.FromContext(ctx).Fatal("Failed to create airgradient-exporter.", zap.Error(err))
 		os.Exit(1)
 	}
-	if err := prometheus.Register(airgradientCollector); err != nil {
-		ilog.FromContext(ctx).Fatal("Failed to register collector.", zap.Error(err))
-		os.Exit(1)
-	}
-	http.Handle("/metrics", promhttp.Handler())
+	http.Handle("/metrics", metricsHandler)
+	http.Handle("/events", metricsHandler.Events())
+
+	server := &http.Server{Addr: listenAddr}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-runCtx.Done()
+		ilog.FromContext(ctx).Info("Stopping server.")
+		shutdownCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			ilog.FromContext(ctx).Error("Failed to stop exporter server gracefully.", zap.Error(err))
+		}
+	}()
 
 	ilog.FromContext(ctx).Info("Starting server", zap.String("addr", listenAddr))
-	if err := http.ListenAndServe(listenAddr, nil); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		ilog.FromContext(ctx).Fatal("Failed to start exporter server", zap.Error(err))
 		os.Exit(1)
 	}
+	<-stopped
+	metricsHandler.Wait()
 	ilog.FromContext(ctx).Info("Exporter server stopped.")
 }
 
@@ -67,6 +104,15 @@ func init() {
 	}
 	endpoint = viper.GetString(endpointFlag)
 
+	exporterCmd.Flags().StringVar(&configFile, configFlag, "", "Path to a config file listing AirGradient devices.")
+	if err := viper.BindPFlag(configFlag, exporterCmd.Flags().Lookup(configFlag)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindEnv(configFlag, "CONFIG_FILE"); err != nil {
+		panic(err)
+	}
+	configFile = viper.GetString(configFlag)
+
 	exporterCmd.Flags().StringVar(&listenAddr, listenAddrFlag, ":9091", "HTTP port to listen on.")
 	if err := viper.BindPFlag(listenAddrFlag, exporterCmd.Flags().Lookup(listenAddrFlag)); err != nil {
 		panic(err)
@@ -0,0 +1,94 @@
+// Package airgradient is a client of the AirGradient local server API.
+// https://github.com/airgradienthq/arduino/blob/master/docs/local-server.md#local-server-api
+package airgradient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Paths of the local server API.
+const (
+	MeasuresPath = "/measures/current"
+	ConfigPath   = "/config"
+)
+
+// Measures are the current measures of a device.
+type Measures struct {
+	SerialNo        string  `json:"serialno"`
+	Wifi            int     `json:"wifi"`
+	PM01            int     `json:"pm01"`
+	PM02            int     `json:"pm02"`
+	PM10            int     `json:"pm10"`
+	PM02Compensated int     `json:"pm02Compensated"`
+	RCO2            int     `json:"rco2"`
+	PM003Count      int     `json:"pm003Count"`
+	ATMP            float64 `json:"atmp"`
+	ATMPCompensated float64 `json:"atmpCompensated"`
+	RHUM            int     `json:"rhum"`
+	RHUMCompensated int     `json:"rhumCompensated"`
+	TVOCIndex       int     `json:"tvocIndex"`
+	TVOCRaw         int     `json:"tvocRaw"`
+	NOXIndex        int     `json:"noxIndex"`
+	NOXRaw          int     `json:"noxRaw"`
+	Boot            int     `json:"boot"`
+	// Deprecated: BootCount is deprecated in favor of Boot
+	BootCount int    `json:"bootCount"`
+	LEDMode   string `json:"ledMode"`
+	Firmware  string `json:"firmware"`
+	Model     string `json:"model"`
+}
+
+// Config holds the subset of the device configuration used by the exporter.
+type Config struct {
+	Country string `json:"country"`
+}
+
+// Client reads AirGradient devices.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client sending requests with the HTTP client.
+func NewClient(c *http.Client) *Client {
+	return &Client{http: c}
+}
+
+// Measures returns the current measures of the device at the endpoint.
+func (c *Client) Measures(ctx context.Context, endpoint *url.URL) (*Measures, error) {
+	var m Measures
+	if err := c.get(ctx, endpoint, MeasuresPath, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Config returns the configuration of the device at the endpoint.
+func (c *Client) Config(ctx context.Context, endpoint *url.URL) (*Config, error) {
+	var cfg Config
+	if err := c.get(ctx, endpoint, ConfigPath, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// get decodes the JSON response of the device to a GET request of the path.
+func (c *Client) get(ctx context.Context, endpoint *url.URL, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint.JoinPath(path).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
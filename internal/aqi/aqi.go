@@ -0,0 +1,155 @@
+// Package aqi computes Air Quality Indices from pollutant concentrations.
+package aqi
+
+import (
+	"math"
+	"sort"
+)
+
+// Pollutant identifies a pollutant an index is computed for.
+type Pollutant string
+
+// Pollutants with an index.
+const (
+	PM25 Pollutant = "pm2.5"
+	PM10 Pollutant = "pm10"
+)
+
+// Averaging is the method used to average pollutant concentrations before an
+// index is computed.
+type Averaging string
+
+// Averaging methods.
+const (
+	AveragingNowCast Averaging = "nowcast"
+	Averaging1h      Averaging = "1h"
+	Averaging24h     Averaging = "24h"
+)
+
+// Standard computes an air quality index from pollutant concentrations.
+type Standard interface {
+	// Name identifies the standard, e.g. us_epa.
+	Name() string
+	// Averagings returns the averaging methods the standard reports indices
+	// for.
+	Averagings() []Averaging
+	// Index computes the index and category of a pollutant from its
+	// concentration in ug/m3 averaged using the method. It returns false if the
+	// pollutant or averaging is not supported or the concentration is invalid.
+	Index(p Pollutant, a Averaging, c float64) (float64, string, bool)
+}
+
+// Breakpoint maps a concentration range onto an index range. Banded indices
+// use the same ILow and IHigh.
+type Breakpoint struct {
+	CLow, CHigh float64
+	ILow, IHigh float64
+	Category    string
+}
+
+// Scale converts concentrations of a pollutant into an index.
+type Scale struct {
+	Breakpoints []Breakpoint
+	// Decimals is the number of decimals concentrations are truncated to.
+	Decimals int
+	// RoundConcentration rounds concentrations to Decimals instead of
+	// truncating them.
+	RoundConcentration bool
+	// Round rounds the interpolated index.
+	Round func(float64) float64
+}
+
+// Index computes the index and category of the concentration.
+func (s Scale) Index(c float64) (float64, string, bool) {
+	if s.RoundConcentration {
+		c = roundTo(c, s.Decimals)
+	} else {
+		c = Truncate(c, s.Decimals)
+	}
+	i, category, ok := Index(s.Breakpoints, c)
+	if !ok {
+		return 0, "", false
+	}
+	if s.Round != nil {
+		i = s.Round(i)
+	}
+	return i, category, true
+}
+
+// Index linearly interpolates the concentration within the breakpoint table. It
+// returns false if the concentration is negative or NaN. Concentrations above
+// the table are reported in the highest category at its upper index.
+func Index(breakpoints []Breakpoint, c float64) (float64, string, bool) {
+	if math.IsNaN(c) || c < 0 || len(breakpoints) == 0 {
+		return 0, "", false
+	}
+	for i, bp := range breakpoints {
+		// Concentrations between the truncated upper bound of a breakpoint and
+		// the lower bound of the next one belong to the lower breakpoint.
+		if c <= bp.CHigh || (i+1 < len(breakpoints) && c < breakpoints[i+1].CLow) {
+			c = math.Min(c, bp.CHigh)
+			if c < bp.CLow {
+				return bp.ILow, bp.Category, true
+			}
+			return (bp.IHigh-bp.ILow)/(bp.CHigh-bp.CLow)*(c-bp.CLow) + bp.ILow, bp.Category, true
+		}
+	}
+	last := breakpoints[len(breakpoints)-1]
+	return last.IHigh, last.Category, true
+}
+
+// Truncate truncates the concentration to the provided number of decimals.
+func Truncate(c float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Trunc(c*p) / p
+}
+
+func roundTo(c float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(c*p) / p
+}
+
+// tableStandard is a Standard defined by a scale per averaging and pollutant.
+type tableStandard struct {
+	name       string
+	averagings []Averaging
+	scales     map[Averaging]map[Pollutant]Scale
+}
+
+func (t *tableStandard) Name() string {
+	return t.name
+}
+
+func (t *tableStandard) Averagings() []Averaging {
+	return t.averagings
+}
+
+func (t *tableStandard) Index(p Pollutant, a Averaging, c float64) (float64, string, bool) {
+	s, ok := t.scales[a][p]
+	if !ok {
+		return 0, "", false
+	}
+	return s.Index(c)
+}
+
+var standards = make(map[string]Standard)
+
+func register(s Standard) {
+	standards[s.Name()] = s
+}
+
+// Lookup returns the standard with the provided name.
+func Lookup(name string) (Standard, bool) {
+	s, ok := standards[name]
+	return s, ok
+}
+
+// Names returns the names of the supported standards.
+func Names() []string {
+	names := make([]string, 0, len(standards))
+	for name := range standards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
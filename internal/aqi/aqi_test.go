@@ -0,0 +1,242 @@
+package aqi
+
+import (
+	"math"
+	"testing"
+)
+
+type indexTest struct {
+	pollutant Pollutant
+	c         float64
+	want      float64
+	category  string
+}
+
+func testStandard(t *testing.T, name string, a Averaging, tests []indexTest) {
+	t.Helper()
+	s, ok := Lookup(name)
+	if !ok {
+		t.Fatalf("Lookup(%q) failed", name)
+	}
+	for _, tt := range tests {
+		got, category, ok := s.Index(tt.pollutant, a, tt.c)
+		if !ok || got != tt.want || category != tt.category {
+			t.Errorf("%s %s index of %v %s = %v, %q, %v, want %v, %q", name, a, tt.c, tt.pollutant, got, category, ok, tt.want, tt.category)
+		}
+	}
+}
+
+func TestUSEPA(t *testing.T) {
+	tests := []indexTest{
+		{PM25, 0, 0, CategoryGood},
+		{PM25, 9.0, 50, CategoryGood},
+		// Concentrations are truncated to 0.1 ug/m3.
+		{PM25, 9.09, 50, CategoryGood},
+		{PM25, 9.1, 51, CategoryModerate},
+		{PM25, 12.0, 56, CategoryModerate},
+		{PM25, 35.4, 100, CategoryModerate},
+		{PM25, 35.5, 101, CategoryUnhealthyForSensitiveGroups},
+		{PM25, 55.4, 150, CategoryUnhealthyForSensitiveGroups},
+		{PM25, 55.5, 151, CategoryUnhealthy},
+		{PM25, 125.4, 200, CategoryUnhealthy},
+		{PM25, 125.5, 201, CategoryVeryUnhealthy},
+		{PM25, 225.4, 300, CategoryVeryUnhealthy},
+		{PM25, 225.5, 301, CategoryHazardous},
+		{PM25, 325.4, 500, CategoryHazardous},
+		{PM25, 600, 500, CategoryHazardous},
+		{PM10, 54, 50, CategoryGood},
+		{PM10, 54.9, 50, CategoryGood},
+		{PM10, 55, 51, CategoryModerate},
+		{PM10, 154, 100, CategoryModerate},
+		{PM10, 155, 101, CategoryUnhealthyForSensitiveGroups},
+		{PM10, 254, 150, CategoryUnhealthyForSensitiveGroups},
+		{PM10, 354, 200, CategoryUnhealthy},
+		{PM10, 424, 300, CategoryVeryUnhealthy},
+		{PM10, 604, 500, CategoryHazardous},
+	}
+	testStandard(t, USEPA, AveragingNowCast, tests)
+	testStandard(t, USEPA, Averaging24h, tests)
+}
+
+func TestEUCAQI(t *testing.T) {
+	testStandard(t, EUCAQI, Averaging1h, []indexTest{
+		{PM25, 0, 0, "very_low"},
+		{PM25, 15, 25, "very_low"},
+		{PM25, 20, 33, "low"},
+		{PM25, 30, 50, "low"},
+		{PM25, 55, 75, "medium"},
+		{PM25, 110, 100, "high"},
+		{PM25, 220, 125, "very_high"},
+		{PM25, 500, 125, "very_high"},
+		{PM10, 25, 25, "very_low"},
+		{PM10, 50, 50, "low"},
+		{PM10, 90, 75, "medium"},
+		{PM10, 180, 100, "high"},
+		{PM10, 360, 125, "very_high"},
+	})
+}
+
+func TestUKDAQI(t *testing.T) {
+	testStandard(t, UKDAQI, Averaging24h, []indexTest{
+		{PM25, 0, 1, "low"},
+		{PM25, 11, 1, "low"},
+		// Means are rounded to whole ug/m3 before banding.
+		{PM25, 11.4, 1, "low"},
+		{PM25, 11.5, 2, "low"},
+		{PM25, 12, 2, "low"},
+		{PM25, 35, 3, "low"},
+		{PM25, 36, 4, "moderate"},
+		{PM25, 53, 6, "moderate"},
+		{PM25, 54, 7, "high"},
+		{PM25, 70, 9, "high"},
+		{PM25, 71, 10, "very_high"},
+		{PM10, 16, 1, "low"},
+		{PM10, 17, 2, "low"},
+		{PM10, 50, 3, "low"},
+		{PM10, 75, 6, "moderate"},
+		{PM10, 100, 9, "high"},
+		{PM10, 100.5, 10, "very_high"},
+	})
+}
+
+func TestINNAQI(t *testing.T) {
+	testStandard(t, INNAQI, Averaging24h, []indexTest{
+		{PM25, 30, 50, "good"},
+		{PM25, 31, 51, "satisfactory"},
+		{PM25, 45, 75, "satisfactory"},
+		{PM25, 60, 100, "satisfactory"},
+		{PM25, 90, 200, "moderate"},
+		{PM25, 120, 300, "poor"},
+		{PM25, 250, 400, "very_poor"},
+		{PM25, 380, 500, "severe"},
+		{PM10, 50, 50, "good"},
+		{PM10, 100, 100, "satisfactory"},
+		{PM10, 250, 200, "moderate"},
+		{PM10, 350, 300, "poor"},
+		{PM10, 430, 400, "very_poor"},
+		{PM10, 510, 500, "severe"},
+	})
+}
+
+func TestCNAQI(t *testing.T) {
+	testStandard(t, CNAQI, Averaging24h, []indexTest{
+		{PM25, 35, 50, "excellent"},
+		// Individual indices are rounded up.
+		{PM25, 36, 52, "good"},
+		{PM25, 75, 100, "good"},
+		{PM25, 115, 150, "lightly_polluted"},
+		{PM25, 150, 200, "moderately_polluted"},
+		{PM25, 250, 300, "heavily_polluted"},
+		{PM25, 350, 400, "severely_polluted"},
+		{PM25, 500, 500, "severely_polluted"},
+		{PM10, 50, 50, "excellent"},
+		{PM10, 150, 100, "good"},
+		{PM10, 250, 150, "lightly_polluted"},
+		{PM10, 350, 200, "moderately_polluted"},
+		{PM10, 420, 300, "heavily_polluted"},
+		{PM10, 600, 500, "severely_polluted"},
+	})
+}
+
+func TestCAAQHI(t *testing.T) {
+	testStandard(t, CAAQHI, Averaging1h, []indexTest{
+		{PM25, 0, 1, "low"},
+		{PM25, 10, 1, "low"},
+		{PM25, 10.1, 2, "low"},
+		{PM25, 35, 4, "moderate"},
+		{PM25, 65, 7, "high"},
+		{PM25, 100, 10, "high"},
+		{PM25, 100.1, 11, "very_high"},
+	})
+}
+
+func TestUnsupported(t *testing.T) {
+	s, _ := Lookup(CAAQHI)
+	if _, _, ok := s.Index(PM10, Averaging1h, 10); ok {
+		t.Error("ca_aqhi reported a PM10 index")
+	}
+	if _, _, ok := s.Index(PM25, Averaging24h, 10); ok {
+		t.Error("ca_aqhi reported a 24h index")
+	}
+	if _, ok := Lookup("unknown"); ok {
+		t.Error("Lookup of an unknown standard succeeded")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	breakpoints := []Breakpoint{
+		{0, 9, 0, 50, "a"},
+		{10, 20, 51, 100, "b"},
+	}
+	tests := []struct {
+		c        float64
+		want     float64
+		category string
+		ok       bool
+	}{
+		{0, 0, "a", true},
+		{4.5, 25, "a", true},
+		// Concentrations between breakpoints belong to the lower one.
+		{9.5, 50, "a", true},
+		{10, 51, "b", true},
+		{15, 75.5, "b", true},
+		{25, 100, "b", true},
+		{-1, 0, "", false},
+		{math.NaN(), 0, "", false},
+	}
+	for _, tt := range tests {
+		got, category, ok := Index(breakpoints, tt.c)
+		if got != tt.want || category != tt.category || ok != tt.ok {
+			t.Errorf("Index(%v) = %v, %q, %v, want %v, %q, %v", tt.c, got, category, ok, tt.want, tt.category, tt.ok)
+		}
+	}
+	if _, _, ok := Index(nil, 1); ok {
+		t.Error("Index without breakpoints succeeded")
+	}
+}
+
+func TestNowCast(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name   string
+		hourly []float64
+		want   float64
+		ok     bool
+	}{
+		{"constant", []float64{10, 10, 10, 10}, 10, true},
+		// The weight factor is the minimum over the maximum, 10/40, raised to
+		// its floor of 0.5: (40 + 0.5*20 + 0.25*10) / (1 + 0.5 + 0.25).
+		{"weight floor", []float64{40, 20, 10}, 30, true},
+		// The weight factor is 8/10: (10 + 0.8*8 + 0.64*8) / (1 + 0.8 + 0.64).
+		{"weight factor", []float64{10, 8, 8}, 21.52 / 2.44, true},
+		{"missing hour", []float64{10, nan, 10}, 10, true},
+		{"clean air", []float64{0, 0, 0}, 0, true},
+		// Only the 12 most recent hours are used.
+		{"older hours", []float64{10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 1000}, 10, true},
+		{"two recent hours missing", []float64{10, nan, nan, 10}, 0, false},
+		{"one hour", []float64{10}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := NowCast(tt.hourly)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: NowCast(%v) = %v, %v, want %v, %v", tt.name, tt.hourly, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestForCountry(t *testing.T) {
+	tests := map[string]string{
+		"US": USEPA,
+		"":   USEPA,
+		"DE": EUCAQI,
+		"GB": UKDAQI,
+		"IN": INNAQI,
+		"CN": CNAQI,
+		"CA": CAAQHI,
+	}
+	for country, want := range tests {
+		if got := ForCountry(country); got != want {
+			t.Errorf("ForCountry(%q) = %q, want %q", country, got, want)
+		}
+	}
+}
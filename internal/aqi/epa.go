@@ -0,0 +1,99 @@
+package aqi
+
+import "math"
+
+// USEPA is the name of the US EPA Air Quality Index.
+const USEPA = "us_epa"
+
+// US EPA categories.
+const (
+	CategoryGood                        = "good"
+	CategoryModerate                    = "moderate"
+	CategoryUnhealthyForSensitiveGroups = "unhealthy_for_sensitive_groups"
+	CategoryUnhealthy                   = "unhealthy"
+	CategoryVeryUnhealthy               = "very_unhealthy"
+	CategoryHazardous                   = "hazardous"
+)
+
+// epaPM25 are the US EPA PM2.5 breakpoints in ug/m3 as revised in 2024.
+// https://www.airnow.gov/publications/air-quality-index/technical-assistance-document-for-reporting-the-daily-aqi/
+var epaPM25 = Scale{
+	Breakpoints: []Breakpoint{
+		{0.0, 9.0, 0, 50, CategoryGood},
+		{9.1, 35.4, 51, 100, CategoryModerate},
+		{35.5, 55.4, 101, 150, CategoryUnhealthyForSensitiveGroups},
+		{55.5, 125.4, 151, 200, CategoryUnhealthy},
+		{125.5, 225.4, 201, 300, CategoryVeryUnhealthy},
+		{225.5, 325.4, 301, 500, CategoryHazardous},
+	},
+	Decimals: 1,
+	Round:    math.Round,
+}
+
+// epaPM10 are the US EPA PM10 breakpoints in ug/m3.
+var epaPM10 = Scale{
+	Breakpoints: []Breakpoint{
+		{0, 54, 0, 50, CategoryGood},
+		{55, 154, 51, 100, CategoryModerate},
+		{155, 254, 101, 150, CategoryUnhealthyForSensitiveGroups},
+		{255, 354, 151, 200, CategoryUnhealthy},
+		{355, 424, 201, 300, CategoryVeryUnhealthy},
+		{425, 604, 301, 500, CategoryHazardous},
+	},
+	Decimals: 0,
+	Round:    math.Round,
+}
+
+func init() {
+	register(&tableStandard{
+		name:       USEPA,
+		averagings: []Averaging{AveragingNowCast, Averaging24h},
+		scales: map[Averaging]map[Pollutant]Scale{
+			AveragingNowCast: {PM25: epaPM25, PM10: epaPM10},
+			Averaging24h:     {PM25: epaPM25, PM10: epaPM10},
+		},
+	})
+}
+
+// NowCast computes the US EPA NowCast concentration for particulate matter from
+// up to 12 hourly averages, most recent first. Missing hours are NaN. It returns
+// false if fewer than two of the three most recent hours are available.
+// https://usepa.servicenowservices.com/airnow?id=kb_article_view&sysparm_article=KB0011856
+func NowCast(hourly []float64) (float64, bool) {
+	if len(hourly) > 12 {
+		hourly = hourly[:12]
+	}
+	recent := 0
+	for i := 0; i < len(hourly) && i < 3; i++ {
+		if !math.IsNaN(hourly[i]) {
+			recent++
+		}
+	}
+	if recent < 2 {
+		return 0, false
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, c := range hourly {
+		if math.IsNaN(c) {
+			continue
+		}
+		min = math.Min(min, c)
+		max = math.Max(max, c)
+	}
+	w := 1.0
+	if max > 0 {
+		w = math.Max(min/max, 0.5)
+	}
+
+	var sum, weights float64
+	for i, c := range hourly {
+		if math.IsNaN(c) {
+			continue
+		}
+		weight := math.Pow(w, float64(i))
+		sum += weight * c
+		weights += weight
+	}
+	return sum / weights, true
+}
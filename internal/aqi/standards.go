@@ -0,0 +1,192 @@
+package aqi
+
+import "math"
+
+// Names of the supported standards besides USEPA.
+const (
+	EUCAQI = "eu_caqi"
+	UKDAQI = "uk_daqi"
+	INNAQI = "in_naqi"
+	CNAQI  = "cn_aqi"
+	CAAQHI = "ca_aqhi"
+)
+
+// maxConc bounds the open ended top band of banded indices.
+const maxConc = math.MaxFloat64
+
+// euCAQI is the hourly Common Air Quality Index for background monitoring.
+// https://www.airqualitynow.eu/about_indices_definition.php
+var euCAQI = map[Pollutant]Scale{
+	PM25: {Breakpoints: []Breakpoint{
+		{0, 15, 0, 25, "very_low"},
+		{15, 30, 25, 50, "low"},
+		{30, 55, 50, 75, "medium"},
+		{55, 110, 75, 100, "high"},
+		{110, 220, 100, 125, "very_high"},
+	}, Decimals: 1, Round: math.Round},
+	PM10: {Breakpoints: []Breakpoint{
+		{0, 25, 0, 25, "very_low"},
+		{25, 50, 25, 50, "low"},
+		{50, 90, 50, 75, "medium"},
+		{90, 180, 75, 100, "high"},
+		{180, 360, 100, 125, "very_high"},
+	}, Decimals: 0, Round: math.Round},
+}
+
+// ukDAQI is the UK Daily Air Quality Index computed from 24 hour running
+// means. The bands are defined on whole ug/m3, so means are rounded to the
+// nearest integer as in the published DAQI.
+// https://uk-air.defra.gov.uk/air-pollution/daqi?view=more-info
+var ukDAQI = map[Pollutant]Scale{
+	PM25: {Breakpoints: daqiBands([]float64{11, 23, 35, 41, 47, 53, 58, 64, 70}), RoundConcentration: true},
+	PM10: {Breakpoints: daqiBands([]float64{16, 33, 50, 58, 66, 75, 83, 91, 100}), RoundConcentration: true},
+}
+
+// daqiBands creates the ten DAQI bands from the upper concentration of the
+// first nine bands.
+func daqiBands(upper []float64) []Breakpoint {
+	var bands []Breakpoint
+	low := 0.0
+	for i, high := range upper {
+		index := float64(i + 1)
+		bands = append(bands, Breakpoint{low, high, index, index, daqiCategory(i + 1)})
+		low = high + 1
+	}
+	return append(bands, Breakpoint{low, maxConc, 10, 10, daqiCategory(10)})
+}
+
+func daqiCategory(index int) string {
+	switch {
+	case index <= 3:
+		return "low"
+	case index <= 6:
+		return "moderate"
+	case index <= 9:
+		return "high"
+	}
+	return "very_high"
+}
+
+// inNAQI is the Indian National Air Quality Index computed from 24 hour
+// averages.
+// https://cpcb.nic.in/National-Air-Quality-Index/
+var inNAQI = map[Pollutant]Scale{
+	PM25: {Breakpoints: []Breakpoint{
+		{0, 30, 0, 50, "good"},
+		{31, 60, 51, 100, "satisfactory"},
+		{61, 90, 101, 200, "moderate"},
+		{91, 120, 201, 300, "poor"},
+		{121, 250, 301, 400, "very_poor"},
+		{251, 380, 401, 500, "severe"},
+	}, Decimals: 0, Round: math.Round},
+	PM10: {Breakpoints: []Breakpoint{
+		{0, 50, 0, 50, "good"},
+		{51, 100, 51, 100, "satisfactory"},
+		{101, 250, 101, 200, "moderate"},
+		{251, 350, 201, 300, "poor"},
+		{351, 430, 301, 400, "very_poor"},
+		{431, 510, 401, 500, "severe"},
+	}, Decimals: 0, Round: math.Round},
+}
+
+// cnAQI is the Chinese Ambient Air Quality Index (HJ 633-2012) computed from
+// 24 hour averages. Individual indices are rounded up.
+var cnAQI = map[Pollutant]Scale{
+	PM25: {Breakpoints: []Breakpoint{
+		{0, 35, 0, 50, "excellent"},
+		{35, 75, 50, 100, "good"},
+		{75, 115, 100, 150, "lightly_polluted"},
+		{115, 150, 150, 200, "moderately_polluted"},
+		{150, 250, 200, 300, "heavily_polluted"},
+		{250, 350, 300, 400, "severely_polluted"},
+		{350, 500, 400, 500, "severely_polluted"},
+	}, Decimals: 0, Round: math.Ceil},
+	PM10: {Breakpoints: []Breakpoint{
+		{0, 50, 0, 50, "excellent"},
+		{50, 150, 50, 100, "good"},
+		{150, 250, 100, 150, "lightly_polluted"},
+		{250, 350, 150, 200, "moderately_polluted"},
+		{350, 420, 200, 300, "heavily_polluted"},
+		{420, 500, 300, 400, "severely_polluted"},
+		{500, 600, 400, 500, "severely_polluted"},
+	}, Decimals: 0, Round: math.Ceil},
+}
+
+// caAQHI is the Canadian AQHI+ computed from hourly PM2.5 alone, which is one
+// index point per 10 ug/m3. The full AQHI also requires ozone and nitrogen
+// dioxide concentrations that AirGradient devices do not measure.
+// https://www.canada.ca/en/environment-climate-change/services/air-quality-health-index/wildfire-smoke.html
+var caAQHI = map[Pollutant]Scale{
+	PM25: {Breakpoints: aqhiBands(), Decimals: 1},
+}
+
+func aqhiBands() []Breakpoint {
+	var bands []Breakpoint
+	for i := 1; i <= 10; i++ {
+		category := "low"
+		switch {
+		case i >= 7:
+			category = "high"
+		case i >= 4:
+			category = "moderate"
+		}
+		bands = append(bands, Breakpoint{float64(i-1) * 10, float64(i) * 10, float64(i), float64(i), category})
+	}
+	// Concentrations above 100 ug/m3 are reported as 10+.
+	return append(bands, Breakpoint{100, maxConc, 11, 11, "very_high"})
+}
+
+func init() {
+	register(&tableStandard{
+		name:       EUCAQI,
+		averagings: []Averaging{Averaging1h},
+		scales:     map[Averaging]map[Pollutant]Scale{Averaging1h: euCAQI},
+	})
+	register(&tableStandard{
+		name:       UKDAQI,
+		averagings: []Averaging{Averaging24h},
+		scales:     map[Averaging]map[Pollutant]Scale{Averaging24h: ukDAQI},
+	})
+	register(&tableStandard{
+		name:       INNAQI,
+		averagings: []Averaging{Averaging24h},
+		scales:     map[Averaging]map[Pollutant]Scale{Averaging24h: inNAQI},
+	})
+	register(&tableStandard{
+		name:       CNAQI,
+		averagings: []Averaging{Averaging24h},
+		scales:     map[Averaging]map[Pollutant]Scale{Averaging24h: cnAQI},
+	})
+	register(&tableStandard{
+		name:       CAAQHI,
+		averagings: []Averaging{Averaging1h},
+		scales:     map[Averaging]map[Pollutant]Scale{Averaging1h: caAQHI},
+	})
+}
+
+// euCountries are the ISO 3166-1 alpha-2 codes of countries reporting the
+// European CAQI.
+var euCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true, "DK": true, "EE": true, "ES": true,
+	"FI": true, "FR": true, "GR": true, "HR": true, "HU": true, "IE": true, "IT": true, "LT": true, "LU": true,
+	"LV": true, "MT": true, "NL": true, "PL": true, "PT": true, "RO": true, "SE": true, "SI": true, "SK": true,
+}
+
+// ForCountry returns the name of the standard used in the country identified by
+// its ISO 3166-1 alpha-2 code, defaulting to USEPA.
+func ForCountry(country string) string {
+	switch country {
+	case "GB", "UK":
+		return UKDAQI
+	case "IN":
+		return INNAQI
+	case "CN":
+		return CNAQI
+	case "CA":
+		return CAAQHI
+	}
+	if euCountries[country] {
+		return EUCAQI
+	}
+	return USEPA
+}
@@ -0,0 +1,178 @@
+// Package baseline learns the usual values of a measure for each hour of the
+// week, so that readings can be scored by how unusual they are at that time.
+package baseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Interval is the period readings are averaged over before being learned.
+	Interval = 5 * time.Minute
+	// MaxValues is the number of averages kept per hour of the week, i.e. eight
+	// weeks of history.
+	MaxValues = 8 * int(time.Hour/Interval)
+	// MinValues is the number of averages required before an hour of the week
+	// is learned.
+	MinValues = 6
+
+	hoursPerWeek = 7 * 24
+	// madScale scales the median absolute deviation to the standard deviation
+	// of normally distributed values.
+	madScale = 1.4826
+)
+
+// Baseline holds the recent averages of a measure for each hour of the week.
+type Baseline struct {
+	Hours [hoursPerWeek][]float64 `json:"hours"`
+
+	// bucket is the start of the interval being averaged.
+	bucket time.Time
+	sum    float64
+	n      int
+}
+
+// hourOfWeek returns the local hour of the week of t, from 0 on Sunday midnight.
+func hourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// Add averages a reading into the current interval, learning the average of the
+// previous interval once t is past it. Readings must be added oldest first.
+func (b *Baseline) Add(t time.Time, v float64) {
+	start := t.Truncate(Interval)
+	if !start.Equal(b.bucket) {
+		if b.n > 0 {
+			i := hourOfWeek(b.bucket)
+			b.Hours[i] = append(b.Hours[i], b.sum/float64(b.n))
+			if len(b.Hours[i]) > MaxValues {
+				b.Hours[i] = b.Hours[i][len(b.Hours[i])-MaxValues:]
+			}
+		}
+		b.bucket, b.sum, b.n = start, 0, 0
+	}
+	b.sum += v
+	b.n++
+}
+
+// Stats returns the median and spread of the learned values at the hour of the
+// week of t. The spread is the median absolute deviation scaled to estimate the
+// standard deviation.
+func (b *Baseline) Stats(t time.Time) (median, spread float64, ok bool) {
+	values := b.Hours[hourOfWeek(t)]
+	if len(values) < MinValues {
+		return 0, 0, false
+	}
+	median = medianOf(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	return median, madScale * medianOf(deviations), true
+}
+
+// Deviation returns the number of spreads a value is away from the median, or
+// false if the spread is zero.
+func Deviation(v, median, spread float64) (float64, bool) {
+	if spread == 0 {
+		return 0, false
+	}
+	return (v - median) / spread, true
+}
+
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// Store holds the baselines of measures by device serial number and persists
+// them to a file.
+type Store struct {
+	path string
+
+	mu        sync.Mutex
+	baselines map[string]map[string]*Baseline
+}
+
+// Open loads the baselines persisted at path. A missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, baselines: make(map[string]map[string]*Baseline)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read baselines: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.baselines); err != nil {
+		return nil, fmt.Errorf("could not decode baselines from %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Add adds a reading of the device measure to its baseline.
+func (s *Store) Add(serial, measure string, t time.Time, v float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	measures, ok := s.baselines[serial]
+	if !ok {
+		measures = make(map[string]*Baseline)
+		s.baselines[serial] = measures
+	}
+	b, ok := measures[measure]
+	if !ok {
+		b = &Baseline{}
+		measures[measure] = b
+	}
+	b.Add(t, v)
+}
+
+// Stats returns the median and spread of the device measure at the hour of the
+// week of t.
+func (s *Store) Stats(serial, measure string, t time.Time) (median, spread float64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, found := s.baselines[serial][measure]
+	if !found {
+		return 0, 0, false
+	}
+	return b.Stats(t)
+}
+
+// Save persists the baselines, replacing the file atomically.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := json.Marshal(s.baselines)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
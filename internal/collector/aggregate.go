@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"math"
+	"strings"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const aggregateGroup = "aggregate"
+
+// aggregateLevels are the location levels devices are grouped by. A device is
+// part of a level's group if the level's own location field is set. Unset
+// parent levels are left out of the group's path.
+var aggregateLevels = []struct {
+	name string
+	path func(l *config.Location) []string
+}{
+	{"site", func(l *config.Location) []string { return []string{l.Site} }},
+	{"building", func(l *config.Location) []string { return []string{l.Site, l.Building} }},
+	{"floor", func(l *config.Location) []string { return []string{l.Site, l.Building, l.Floor} }},
+	{"room", func(l *config.Location) []string { return []string{l.Site, l.Building, l.Floor, l.Room} }},
+}
+
+// aggregatedFamilies lists the measure families aggregated across the devices
+// of a group.
+var aggregatedFamilies = []string{"pm02", "rco2", "atmp", "rhum"}
+
+// aggregates computes statistics across devices sharing a location group.
+type aggregates struct {
+	devices  *family
+	measures []aggregateMeasure
+}
+
+type aggregateMeasure struct {
+	source *family
+	family *family
+}
+
+func (c *airgradientCollector) newAggregates() *aggregates {
+	a := &aggregates{
+		devices: c.newSharedFamily("group_devices", aggregateGroup, "Number of devices configured in the location group",
+			"level", "group", "environment"),
+	}
+	for _, f := range c.measureFamilies {
+		for _, name := range aggregatedFamilies {
+			if f.name != name {
+				continue
+			}
+			a.measures = append(a.measures, aggregateMeasure{
+				source: f,
+				family: c.newSharedFamily("group_"+f.name, aggregateGroup,
+					"Mean, min, max and count of reporting devices in the location group for "+f.name,
+					"level", "group", "environment", "stat"),
+			})
+		}
+	}
+	return a
+}
+
+func (c *airgradientCollector) aggregateFamilies() []*family {
+	families := []*family{c.aggregates.devices}
+	for _, m := range c.aggregates.measures {
+		families = append(families, m.family)
+	}
+	return families
+}
+
+type aggregateKey struct {
+	level       string
+	group       string
+	environment string
+}
+
+// groupName joins the set levels of a location path.
+func groupName(path []string) string {
+	var set []string
+	for _, p := range path {
+		if p != "" {
+			set = append(set, p)
+		}
+	}
+	return strings.Join(set, "/")
+}
+
+// collectAggregates emits the statistics of every location group. Devices that
+// could not be scraped count towards the configured devices of a group but not
+// towards its statistics, nor do devices not exporting the aggregated measure.
+func (c *airgradientCollector) collectAggregates(ch chan<- prometheus.Metric, scrapes []*scrape, sel selector) {
+	groups := make(map[aggregateKey][]int)
+	for i, d := range c.devices {
+		if d.location == nil {
+			continue
+		}
+		environment := d.environment()
+		for _, level := range aggregateLevels {
+			path := level.path(d.location)
+			if path[len(path)-1] == "" {
+				continue
+			}
+			k := aggregateKey{level: level.name, group: groupName(path), environment: environment}
+			groups[k] = append(groups[k], i)
+		}
+	}
+
+	for k, devices := range groups {
+		c.emitShared(ch, sel, c.aggregates.devices, float64(len(devices)), k.level, k.group, k.environment)
+
+		for _, m := range c.aggregates.measures {
+			var sum float64
+			var count int
+			min, max := math.Inf(1), math.Inf(-1)
+			for _, i := range devices {
+				s := scrapes[i]
+				if s == nil || !s.exports(m.source) {
+					continue
+				}
+				v := m.source.value(s.measures)
+				sum += v
+				count++
+				min = math.Min(min, v)
+				max = math.Max(max, v)
+			}
+
+			c.emitShared(ch, sel, m.family, float64(count), k.level, k.group, k.environment, "count")
+			if count == 0 {
+				continue
+			}
+			c.emitShared(ch, sel, m.family, sum/float64(count), k.level, k.group, k.environment, "mean")
+			c.emitShared(ch, sel, m.family, min, k.level, k.group, k.environment, "min")
+			c.emitShared(ch, sel, m.family, max, k.level, k.group, k.environment, "max")
+		}
+	}
+}
@@ -2,220 +2,523 @@ package collector
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/dtrejod/airgradient-exporter/internal/airgradient"
+	"github.com/dtrejod/airgradient-exporter/internal/aqi"
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/correction"
 	"github.com/dtrejod/airgradient-exporter/internal/ilog"
+	"github.com/dtrejod/airgradient-exporter/internal/mold"
+	"github.com/dtrejod/airgradient-exporter/internal/ventilation"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
+const (
+	namespace = "airgradient"
+
+	// requestTimeout bounds a request to a device, so that a device that does
+	// not respond does not hold up scrapes.
+	requestTimeout = 10 * time.Second
+)
+
+var (
+	pmSensors          = []sensor{sensorPMS5003, sensorPMS5003T}
+	co2Sensors         = []sensor{sensorS8}
+	temperatureSensors = []sensor{sensorSHT, sensorPMS5003T}
+	vocSensors         = []sensor{sensorSGP41}
+)
+
+// family describes a metric family exported once per device.
+type family struct {
+	// name is the family name without the airgradient_ prefix, e.g. pm02.
+	name string
+	// group is the family's metric group used for filtering, e.g. pm.
+	group string
+	// labels are the variable label names before relabeling.
+	labels    []string
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	// sensors lists the sensors of which at least one must be fitted for the
+	// family to be exported. An empty list means the family is always exported.
+	sensors []sensor
+	// value extracts the family's value from the device measures. It is nil for
+	// families that are not read directly from the measures.
+	value func(m *measures) float64
+}
+
+// newFamily creates a family labeled by serial number followed by the provided
+// labels.
+func (c *airgradientCollector) newFamily(name, group, help string, valueType prometheus.ValueType, sensors []sensor, value func(m *measures) float64, labels ...string) *family {
+	labels = append([]string{serialLabel}, labels...)
+	return &family{
+		name:      name,
+		group:     group,
+		labels:    labels,
+		desc:      c.relabeler.newDesc(namespace+"_"+name, help, labels),
+		valueType: valueType,
+		sensors:   sensors,
+		value:     value,
+	}
+}
+
+// newSharedFamily creates a gauge family spanning several devices, which is
+// therefore not labeled by a single serial number.
+func (c *airgradientCollector) newSharedFamily(name, group, help string, labels ...string) *family {
+	return &family{
+		name:      name,
+		group:     group,
+		labels:    labels,
+		desc:      c.relabeler.newDesc(namespace+"_"+name, help, labels),
+		valueType: prometheus.GaugeValue,
+	}
+}
+
 // NewAirGradient creates a new collector for the AirGradient local server API.
 // https://github.com/airgradienthq/arduino/blob/master/docs/local-server.md#local-server-api
-func NewAirGradient(ctx context.Context, endpoint string) (prometheus.Collector, error) {
-	e, err := url.Parse(endpoint)
+func NewAirGradient(ctx context.Context, cfg config.Config) (prometheus.Collector, error) {
+	return newAirGradient(ctx, cfg)
+}
+
+func newAirGradient(ctx context.Context, cfg config.Config) (*airgradientCollector, error) {
+	r, err := newRelabeler(cfg.Relabel)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse airgradient endpoint into url: %w", err)
-	}
-
-	return &airgradientCollector{
-		ctx:      ctx,
-		client:   &http.Client{},
-		endpoint: e,
-		deviceInfoDesc: prometheus.NewDesc(
-			"airgradient_device_info",
-			"Device information",
-			[]string{"serialno", "firmware", "model", "ledmode"},
-			nil,
-		),
-		wifiDesc: prometheus.NewDesc(
-			"airgradient_wifi",
-			"WiFi signal strength",
-			[]string{"serialno"},
-			nil,
-		),
-		pm01Desc: prometheus.NewDesc(
-			"airgradient_pm01",
-			"PM1 in ug/m3",
-			[]string{"serialno"},
-			nil,
-		),
-		pm02Desc: prometheus.NewDesc(
-			"airgradient_pm02",
-			"PM2.5 in ug/m3",
-			[]string{"serialno"},
-			nil,
-		),
-		pm10Desc: prometheus.NewDesc(
-			"airgradient_pm10",
-			"PM10 in ug/m3",
-			[]string{"serialno"},
-			nil,
-		),
-		pm02CompensatedDesc: prometheus.NewDesc(
-			"airgradient_pm02_compensated",
-			"PM2.5 in ug/m3 with correction applied",
-			[]string{"serialno"},
-			nil,
-		),
-		rco2Desc: prometheus.NewDesc(
-			"airgradient_rco2",
-			"CO2 in ppm",
-			[]string{"serialno"},
-			nil,
-		),
-		pm003CountDesc: prometheus.NewDesc(
-			"airgradient_pm003_count",
-			"Particle count per dL",
-			[]string{"serialno"},
-			nil,
-		),
-		atmpDesc: prometheus.NewDesc(
-			"airgradient_atmp",
-			"Temperature in Degrees Celsius",
-			[]string{"serialno"},
-			nil,
-		),
-		atmpCompensatedDesc: prometheus.NewDesc(
-			"airgradient_atmp_compensated",
-			"Temperature in Degrees Celsius with correction applied",
-			[]string{"serialno"},
-			nil,
-		),
-		rhumDesc: prometheus.NewDesc(
-			"airgradient_rhum",
-			"Relative Humidity",
-			[]string{"serialno"},
-			nil,
-		),
-		rhumCompensatedDesc: prometheus.NewDesc(
-			"airgradient_rhum_compensated",
-			"Relative Humidity with correction applied",
-			[]string{"serialno"},
-			nil,
-		),
-		tvocIndexDesc: prometheus.NewDesc(
-			"airgradient_tvoc_index",
-			"Senisiron VOC Index",
-			[]string{"serialno"},
-			nil,
-		),
-		tvocRawDesc: prometheus.NewDesc(
-			"airgradient_tvoc_raw",
-			"VOC raw value",
-			[]string{"serialno"},
-			nil,
-		),
-		noxIndexDesc: prometheus.NewDesc(
-			"airgradient_nox_index",
-			"Senisirion NOx Index",
-			[]string{"serialno"},
-			nil,
-		),
-		noxRawDesc: prometheus.NewDesc(
-			"airgradient_nox_raw",
-			"NOx raw value",
-			[]string{"serialno"},
-			nil,
-		),
-		bootDesc: prometheus.NewDesc(
-			"airgradient_boot_total",
-			"The total uptime of the device in minutes",
-			[]string{"serialno"},
-			nil,
-		),
-	}, nil
+		return nil, fmt.Errorf("invalid relabel config: %w", err)
+	}
+
+	c := &airgradientCollector{
+		ctx:          ctx,
+		client:       airgradient.NewClient(&http.Client{Timeout: requestTimeout}),
+		relabeler:    r,
+		pollInterval: cfg.PollInterval,
+		maxHold:      max(defaultMaxHold, 2*cfg.PollInterval),
+	}
+	c.deviceInfoFamily = c.newFamily("device_info", "device", "Device information", prometheus.GaugeValue, nil, nil,
+		"firmware", "model", "ledmode")
+	c.capabilityFamily = c.newFamily("device_capability", "device", "Sensors fitted to the device as decoded from its model", prometheus.GaugeValue, nil, nil,
+		"sensor")
+	c.locationFamily = c.newFamily("device_location_info", "device", "Configured location of the device", prometheus.GaugeValue, nil, nil,
+		"site", "building", "floor", "room", "environment", "latitude", "longitude", "altitude")
+	c.measureFamilies = []*family{
+		c.newFamily("wifi", "device", "WiFi signal strength", prometheus.GaugeValue, nil,
+			func(m *measures) float64 { return float64(m.Wifi) }),
+		c.newFamily("pm01", "pm", "PM1 in ug/m3", prometheus.GaugeValue, pmSensors,
+			func(m *measures) float64 { return float64(m.PM01) }),
+		c.newFamily("pm02", "pm", "PM2.5 in ug/m3", prometheus.GaugeValue, pmSensors,
+			func(m *measures) float64 { return float64(m.PM02) }),
+		c.newFamily("pm10", "pm", "PM10 in ug/m3", prometheus.GaugeValue, pmSensors,
+			func(m *measures) float64 { return float64(m.PM10) }),
+		c.newFamily("pm02_compensated", "pm", "PM2.5 in ug/m3 with correction applied", prometheus.GaugeValue, pmSensors,
+			func(m *measures) float64 { return float64(m.PM02Compensated) }),
+		c.newFamily("pm02_epa_corrected", "pm", "PM2.5 in ug/m3 with the US EPA nationwide PMS5003 correction applied", prometheus.GaugeValue, pmSensors,
+			func(m *measures) float64 { return correction.EPAPMS5003(float64(m.PM02), float64(m.RHUM)) }),
+		c.newFamily("rco2", "co2", "CO2 in ppm", prometheus.GaugeValue, co2Sensors,
+			func(m *measures) float64 { return float64(m.RCO2) }),
+		c.newFamily("pm003_count", "pm", "Particle count per dL", prometheus.GaugeValue, pmSensors,
+			func(m *measures) float64 { return float64(m.PM003Count) }),
+		c.newFamily("atmp", "temperature", "Temperature in Degrees Celsius", prometheus.GaugeValue, temperatureSensors,
+			func(m *measures) float64 { return m.ATMP }),
+		c.newFamily("atmp_compensated", "temperature", "Temperature in Degrees Celsius with correction applied", prometheus.GaugeValue, temperatureSensors,
+			func(m *measures) float64 { return m.ATMPCompensated }),
+		c.newFamily("rhum", "humidity", "Relative Humidity", prometheus.GaugeValue, temperatureSensors,
+			func(m *measures) float64 { return float64(m.RHUM) }),
+		c.newFamily("rhum_compensated", "humidity", "Relative Humidity with correction applied", prometheus.GaugeValue, temperatureSensors,
+			func(m *measures) float64 { return float64(m.RHUMCompensated) }),
+		c.newFamily("tvoc_index", "voc", "Senisiron VOC Index", prometheus.GaugeValue, vocSensors,
+			func(m *measures) float64 { return float64(m.TVOCIndex) }),
+		c.newFamily("tvoc_raw", "voc", "VOC raw value", prometheus.GaugeValue, vocSensors,
+			func(m *measures) float64 { return float64(m.TVOCRaw) }),
+		c.newFamily("nox_index", "nox", "Senisirion NOx Index", prometheus.GaugeValue, vocSensors,
+			func(m *measures) float64 { return float64(m.NOXIndex) }),
+		c.newFamily("nox_raw", "nox", "NOx raw value", prometheus.GaugeValue, vocSensors,
+			func(m *measures) float64 { return float64(m.NOXRaw) }),
+		c.newFamily("boot_total", "device", "The total uptime of the device in minutes", prometheus.CounterValue, nil,
+			func(m *measures) float64 { return float64(m.Boot) }),
+	}
+
+	c.aggregates = c.newAggregates()
+	c.pairs = c.newPairs(cfg.Pairs)
+	c.aqi = c.newAQIFamilies()
+	c.psychro = c.newPsychroFamilies()
+	c.comfort = c.newComfortFamilies()
+	c.ventilation = c.newVentilationFamilies()
+	c.occupancy = c.newOccupancyFamilies()
+	c.infection = c.newInfectionFamilies()
+	c.mold = c.newMoldFamilies()
+	windows := cfg.RollingWindows
+	if windows == nil {
+		windows = config.DefaultRollingWindows
+	}
+	c.rolling = c.newRolling(windows)
+	c.calibrations = c.newCalibrations(cfg.Calibrations)
+	thresholds, doses := cfg.Thresholds, cfg.Doses
+	if thresholds == nil {
+		thresholds = config.DefaultThresholds
+	}
+	if doses == nil {
+		doses = config.DefaultDoses
+	}
+	if c.exposure, err = c.newExposure(thresholds, doses); err != nil {
+		return nil, err
+	}
+	stuckReadings := cfg.SensorHealth.StuckReadings
+	if stuckReadings == 0 {
+		stuckReadings = config.DefaultStuckReadings
+	}
+	c.sensorHealth = c.newSensorHealth(stuckReadings)
+	c.warmup = c.newWarmup(cfg.Warmup)
+	c.events = c.newEvents()
+	c.forecasts = c.newForecasts()
+	if c.baselines, err = c.newBaselines(cfg.Baselines); err != nil {
+		return nil, err
+	}
+	if c.aqiStandards, err = lookupStandards(cfg.AQIStandards); err != nil {
+		return nil, err
+	}
+	if c.derived, err = c.newDerived(cfg.DerivedMetrics); err != nil {
+		return nil, err
+	}
+	if err := c.relabeler.validate(c.allFamilies()); err != nil {
+		return nil, fmt.Errorf("invalid relabel config: %w", err)
+	}
+
+	for _, d := range cfg.Devices {
+		e, err := url.Parse(d.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse airgradient endpoint into url: %w", err)
+		}
+		if err := c.validateSelectors(d.Include); err != nil {
+			return nil, fmt.Errorf("invalid include for %s: %w", d.Endpoint, err)
+		}
+		if err := c.validateSelectors(d.Exclude); err != nil {
+			return nil, fmt.Errorf("invalid exclude for %s: %w", d.Endpoint, err)
+		}
+		standards, err := lookupStandards(d.AQIStandards)
+		if err != nil {
+			return nil, fmt.Errorf("invalid aqi standards for %s: %w", d.Endpoint, err)
+		}
+		c.devices = append(c.devices, &device{
+			endpoint:    e,
+			selector:    selector{include: d.Include, exclude: d.Exclude},
+			location:    d.Location,
+			history:     newHistory(c.retention()),
+			standards:   standards,
+			comfort:     d.Comfort,
+			ventilation: d.Ventilation,
+			occupancy:   d.Occupancy,
+			infection:   d.InfectionRisk,
+			mold:        d.Mold,
+		})
+	}
+	return c, nil
 }
 
-type airgradientCollector struct {
-	ctx      context.Context
-	client   *http.Client
+// retention returns the history required by the collector's derived metrics.
+func (c *airgradientCollector) retention() time.Duration {
+	retention := max(aqiRetention, ventilationLookback)
+	for _, p := range c.pairs.pairs {
+		if p.window > retention {
+			retention = p.window
+		}
+	}
+	for _, w := range c.rolling.windows {
+		if w > retention {
+			retention = w
+		}
+	}
+	return retention
+}
+
+// device is a single AirGradient device polled by the collector.
+type device struct {
 	endpoint *url.URL
+	selector selector
+	location *config.Location
+	history  *history
+	// standards are the air quality index standards configured for the device.
+	standards []aqi.Standard
+	comfort   *config.Comfort
+	// ventilation describes the device's room, if configured.
+	ventilation *config.Ventilation
+	occupancy   *config.Occupancy
+	infection   *config.InfectionRisk
+	mold        *config.Mold
+
+	mu sync.Mutex
+	// model is the device model as last reported by the device.
+	model string
+	// country is the country configured on the device, if it was retrieved.
+	country *string
+	// countryErr is the error of the last failed country lookup, which is not
+	// retried before countryRetry.
+	countryErr     error
+	countryRetry   time.Time
+	countryBackoff time.Duration
+	// decay is the latest CO2 decay found in the device history.
+	decay *ventilation.Decay
+	// moldModel is the mold growth model of the device's room, updated with
+	// the readings up to moldUpdated.
+	moldModel   mold.Model
+	moldUpdated time.Time
+	exposure    exposureState
+	// baselineUpdated is the time of the latest reading learned.
+	baselineUpdated time.Time
+	health          healthState
+	boot            bootState
+	events          eventState
+}
+
+func (d *device) setModel(model string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.model = model
+}
+
+// environment returns whether the device is installed indoor or outdoor, as
+// configured or otherwise inferred from its last reported model.
+func (d *device) environment() string {
+	if d.location != nil && d.location.Environment != "" {
+		return d.location.Environment
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return environmentFromModel(d.model)
+}
+
+type airgradientCollector struct {
+	ctx       context.Context
+	client    *airgradient.Client
+	devices   []*device
+	relabeler *relabeler
+	// pollInterval is the interval devices are polled at in the background,
+	// or zero if they are polled when scraped.
+	pollInterval time.Duration
+	// maxHold is the longest time a reading is assumed to hold until the next
+	// one, e.g. when averaging or accumulating readings.
+	maxHold time.Duration
 
-	deviceInfoDesc      *prometheus.Desc
-	wifiDesc            *prometheus.Desc
-	pm01Desc            *prometheus.Desc
-	pm02Desc            *prometheus.Desc
-	pm10Desc            *prometheus.Desc
-	pm02CompensatedDesc *prometheus.Desc
-	rco2Desc            *prometheus.Desc
-	pm003CountDesc      *prometheus.Desc
-	atmpDesc            *prometheus.Desc
-	atmpCompensatedDesc *prometheus.Desc
-	rhumDesc            *prometheus.Desc
-	rhumCompensatedDesc *prometheus.Desc
-	tvocIndexDesc       *prometheus.Desc
-	tvocRawDesc         *prometheus.Desc
-	noxIndexDesc        *prometheus.Desc
-	noxRawDesc          *prometheus.Desc
-	bootDesc            *prometheus.Desc
+	deviceInfoFamily *family
+	capabilityFamily *family
+	locationFamily   *family
+	measureFamilies  []*family
+	aggregates       *aggregates
+	pairs            *pairs
+	aqi              *aqiFamilies
+	psychro          []psychroFamily
+	comfort          *comfortFamilies
+	ventilation      *ventilationFamilies
+	occupancy        *occupancyFamilies
+	infection        *infectionFamilies
+	mold             *moldFamilies
+	exposure         *exposure
+	baselines        *baselines
+	sensorHealth     *sensorHealth
+	warmup           *warmup
+	events           *events
+	forecasts        *forecasts
+	rolling          *rolling
+	calibrations     *calibrations
+	derived          []derivedMetric
+	// aqiStandards are the air quality index standards computed for devices
+	// without their own.
+	aqiStandards []aqi.Standard
+}
+
+// scrape holds the state of collecting the metrics of a single device.
+type scrape struct {
+	device   *device
+	t        time.Time
+	measures *measures
+	caps     capabilities
+	selector selector
+	// warming holds the sensors whose series are suppressed while they warm
+	// up.
+	warming capabilities
+}
+
+func (c *airgradientCollector) allFamilies() []*family {
+	families := append([]*family{c.deviceInfoFamily, c.capabilityFamily, c.locationFamily}, c.measureFamilies...)
+	families = append(families, c.aggregateFamilies()...)
+	families = append(families, c.pairs.families()...)
+	families = append(families, c.aqi.families()...)
+	families = append(families, psychroFamilies(c.psychro)...)
+	families = append(families, c.comfort.families()...)
+	families = append(families, c.ventilation.families()...)
+	families = append(families, c.occupancy.families()...)
+	families = append(families, c.infection.families()...)
+	families = append(families, c.mold.families()...)
+	families = append(families, c.exposure.families()...)
+	families = append(families, c.baselines.families()...)
+	families = append(families, c.sensorHealth.family)
+	families = append(families, c.warmup.families()...)
+	families = append(families, c.events.family)
+	families = append(families, c.forecasts.family)
+	families = append(families, c.rolling.families()...)
+	families = append(families, derivedFamilies(c.derived)...)
+	return append(families, c.calibrations.all()...)
+}
+
+// validateSelectors returns an error if the patterns do not match the
+// collector's metric families.
+func (c *airgradientCollector) validateSelectors(patterns []string) error {
+	return validatePatterns(patterns, c.allFamilies())
+}
+
+// filtered returns a view of the collector exporting only the families matched
+// by the selector.
+func (c *airgradientCollector) filtered(sel selector) prometheus.Collector {
+	return &filteredCollector{airgradientCollector: c, selector: sel}
 }
 
 func (c *airgradientCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- c.deviceInfoDesc
-	ch <- c.wifiDesc
-	ch <- c.pm01Desc
-	ch <- c.pm02Desc
-	ch <- c.pm10Desc
-	ch <- c.pm02CompensatedDesc
-	ch <- c.rco2Desc
-	ch <- c.pm003CountDesc
-	ch <- c.atmpDesc
-	ch <- c.atmpCompensatedDesc
-	ch <- c.rhumDesc
-	ch <- c.rhumCompensatedDesc
-	ch <- c.tvocIndexDesc
-	ch <- c.tvocRawDesc
-	ch <- c.noxIndexDesc
-	ch <- c.noxRawDesc
-	ch <- c.bootDesc
+	for _, f := range c.allFamilies() {
+		ch <- f.desc
+	}
 }
 
 func (c *airgradientCollector) Collect(ch chan<- prometheus.Metric) {
-	m, err := c.getMeasures(c.ctx)
+	c.collect(ch, selector{})
+}
+
+func (c *airgradientCollector) collect(ch chan<- prometheus.Metric, sel selector) {
+	// scrapes holds the scrape of each device, or nil if the device could not
+	// be scraped.
+	scrapes := make([]*scrape, len(c.devices))
+	var wg sync.WaitGroup
+	for i, d := range c.devices {
+		wg.Add(1)
+		go func(i int, d *device) {
+			defer wg.Done()
+			scrapes[i] = c.collectDevice(ch, d, sel)
+		}(i, d)
+	}
+	wg.Wait()
+
+	c.collectAggregates(ch, scrapes, sel)
+	c.collectPairs(ch, scrapes, sel)
+}
+
+func (c *airgradientCollector) collectDevice(ch chan<- prometheus.Metric, d *device, sel selector) *scrape {
+	r, err := c.read(d)
 	if err != nil {
-		ilog.FromContext(c.ctx).Error("Failed to get measures.", zap.Error(err))
+		ilog.FromContext(c.ctx).Error("Failed to get measures.", zap.Stringer("endpoint", d.endpoint), zap.Error(err))
+		return nil
+	}
+	m := r.m
+
+	caps := parseModel(m.Model)
+	if caps == nil {
+		ilog.FromContext(c.ctx).Debug("Unrecognized device model, exporting all metrics.", zap.String("model", m.Model))
+	}
+	s := &scrape{device: d, t: r.t, measures: m, caps: caps, selector: sel}
+
+	c.emit(ch, s, c.deviceInfoFamily, 1, m.Firmware, m.Model, m.LEDMode)
+	c.collectWarmup(ch, s)
+
+	sensors := make([]string, 0, len(caps))
+	for s := range caps {
+		sensors = append(sensors, string(s))
+	}
+	sort.Strings(sensors)
+	for _, sensor := range sensors {
+		c.emit(ch, s, c.capabilityFamily, 1, sensor)
+	}
+
+	if l := d.location; l != nil {
+		c.emit(ch, s, c.locationFamily, 1, l.Site, l.Building, l.Floor, l.Room, d.environment(),
+			formatOptional(l.Latitude), formatOptional(l.Longitude), formatOptional(l.Altitude))
+	}
+
+	for _, f := range c.measureFamilies {
+		c.emit(ch, s, f, f.value(m))
+	}
+	c.collectCalibrated(ch, s)
+	c.collectAQI(ch, s)
+	c.collectPsychro(ch, s)
+	c.collectComfort(ch, s)
+	c.collectVentilation(ch, s)
+	c.collectOccupancy(ch, s)
+	c.collectInfectionRisk(ch, s)
+	c.collectMold(ch, s)
+	c.collectExposure(ch, s)
+	c.collectBaselines(ch, s)
+	c.collectSensorHealth(ch, s)
+	c.collectEvents(ch, s)
+	c.collectRolling(ch, s)
+	c.collectForecasts(ch, s)
+	c.collectDerived(ch, s)
+	return s
+}
+
+// emit sends a metric of the family for the scraped device if the family is
+// selected, supported by the device and not suppressed while its sensors warm
+// up. The relabeled serial number is prepended to the provided label values.
+func (c *airgradientCollector) emit(ch chan<- prometheus.Metric, s *scrape, f *family, v float64, labelValues ...string) {
+	if !s.exports(f) || !s.selector.matches(f) {
 		return
 	}
+	ch <- prometheus.MustNewConstMetric(f.desc, f.valueType, v, append([]string{c.relabeler.serial(s.measures.SerialNo)}, labelValues...)...)
+}
 
-	ch <- prometheus.MustNewConstMetric(c.deviceInfoDesc, prometheus.GaugeValue, 1, m.SerialNo, m.Firmware, m.Model, m.LEDMode)
-	ch <- prometheus.MustNewConstMetric(c.wifiDesc, prometheus.GaugeValue, float64(m.Wifi), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.pm01Desc, prometheus.GaugeValue, float64(m.PM01), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.pm02Desc, prometheus.GaugeValue, float64(m.PM02), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.pm10Desc, prometheus.GaugeValue, float64(m.PM10), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.pm02CompensatedDesc, prometheus.GaugeValue, float64(m.PM02Compensated), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.rco2Desc, prometheus.GaugeValue, float64(m.RCO2), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.pm003CountDesc, prometheus.GaugeValue, float64(m.PM003Count), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.atmpDesc, prometheus.GaugeValue, m.ATMP, m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.atmpCompensatedDesc, prometheus.GaugeValue, m.ATMPCompensated, m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.rhumDesc, prometheus.GaugeValue, float64(m.RHUM), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.rhumCompensatedDesc, prometheus.GaugeValue, float64(m.RHUMCompensated), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.tvocIndexDesc, prometheus.GaugeValue, float64(m.TVOCIndex), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.tvocRawDesc, prometheus.GaugeValue, float64(m.TVOCRaw), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.noxIndexDesc, prometheus.GaugeValue, float64(m.NOXIndex), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.noxRawDesc, prometheus.GaugeValue, float64(m.NOXRaw), m.SerialNo)
-	ch <- prometheus.MustNewConstMetric(c.bootDesc, prometheus.CounterValue, float64(m.Boot), m.SerialNo)
-}
-
-func (c *airgradientCollector) getMeasures(ctx context.Context) (*measures, error) {
-	ilog.FromContext(ctx).Debug("Getting measures from airgradient.")
-	req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint.JoinPath(measuresPath).String(), nil)
-	if err != nil {
-		return nil, err
+// exports reports whether the scraped device exports the family: its hardware
+// supports it, it is not suppressed during warm-up and the device's include and
+// exclude lists select it.
+func (s *scrape) exports(f *family) bool {
+	return s.caps.supports(f.sensors...) && !s.suppressed(f) && s.device.selector.matches(f)
+}
+
+// emitShared sends a metric of a family spanning several devices if the family
+// is selected.
+func (c *airgradientCollector) emitShared(ch chan<- prometheus.Metric, sel selector, f *family, v float64, labelValues ...string) {
+	if !sel.matches(f) {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(f.desc, f.valueType, v, labelValues...)
+}
+
+// formatOptional formats an optional label value, returning an empty string if
+// it is not set.
+func formatOptional(v *float64) string {
+	if v == nil {
+		return ""
 	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// filteredCollector exports the subset of an airgradientCollector's families
+// matched by its selector.
+type filteredCollector struct {
+	*airgradientCollector
+	selector selector
+}
+
+func (c *filteredCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch, c.selector)
+}
 
-	resp, err := c.client.Do(req)
+func (c *airgradientCollector) getMeasures(ctx context.Context, d *device) (*measures, error) {
+	ilog.FromContext(ctx).Debug("Getting measures from airgradient.")
+	m, err := c.client.Measures(ctx, d.endpoint)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	ilog.FromContext(ctx).Debug("Got measures from airgradient.", zap.Any("measures", m))
+	return m, nil
+}
 
-	var m measures
-	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+func (c *airgradientCollector) getDeviceConfig(ctx context.Context, d *device) (*airgradient.Config, error) {
+	ilog.FromContext(ctx).Debug("Getting config from airgradient.")
+	cfg, err := c.client.Config(ctx, d.endpoint)
+	if err != nil {
 		return nil, err
 	}
-	ilog.FromContext(ctx).Debug("Got measures from airgradient.", zap.Any("measures", m))
-	return &m, nil
+	ilog.FromContext(ctx).Debug("Got config from airgradient.", zap.Any("config", cfg))
+	return cfg, nil
 }
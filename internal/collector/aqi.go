@@ -0,0 +1,178 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/aqi"
+	"github.com/dtrejod/airgradient-exporter/internal/ilog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	aqiGroup = "aqi"
+
+	// aqiRetention is the history required to compute the 24 hour AQI.
+	aqiRetention = 24 * time.Hour
+	// min24hHours is the number of hourly averages required for a 24 hour
+	// average, following the EPA 75% completeness criteria.
+	min24hHours = 18
+
+	// Bounds of the backoff between failed device country lookups.
+	countryMinBackoff = time.Minute
+	countryMaxBackoff = time.Hour
+)
+
+// aqiPollutants maps the pollutants an AQI is computed for to the measures
+// holding their concentration.
+var aqiPollutants = []struct {
+	pollutant aqi.Pollutant
+	value     func(m *measures) float64
+}{
+	{aqi.PM25, func(m *measures) float64 { return float64(m.PM02) }},
+	{aqi.PM10, func(m *measures) float64 { return float64(m.PM10) }},
+}
+
+// aqiFamilies exports the Air Quality Indices of a device.
+type aqiFamilies struct {
+	index     *family
+	pollutant *family
+}
+
+func (c *airgradientCollector) newAQIFamilies() *aqiFamilies {
+	return &aqiFamilies{
+		index: c.newFamily("aqi", aqiGroup, "Air Quality Index of the dominant pollutant", prometheus.GaugeValue, pmSensors, nil,
+			"aqi_standard", "method", "dominant_pollutant", "category"),
+		pollutant: c.newFamily("aqi_pollutant", aqiGroup, "Air Quality Index of a pollutant", prometheus.GaugeValue, pmSensors, nil,
+			"aqi_standard", "method", "pollutant", "category"),
+	}
+}
+
+func (a *aqiFamilies) families() []*family {
+	return []*family{a.index, a.pollutant}
+}
+
+// lookupStandards resolves the names of air quality index standards.
+func lookupStandards(names []string) ([]aqi.Standard, error) {
+	var standards []aqi.Standard
+	for _, name := range names {
+		s, ok := aqi.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown aqi standard %q, must be one of %s", name, strings.Join(aqi.Names(), ", "))
+		}
+		standards = append(standards, s)
+	}
+	return standards, nil
+}
+
+// standardsFor returns the air quality index standards computed for the device.
+// Unless configured, these are the US EPA index and the standard used in the
+// country configured on the device.
+func (c *airgradientCollector) standardsFor(d *device) []aqi.Standard {
+	if d.standards != nil {
+		return d.standards
+	}
+	if c.aqiStandards != nil {
+		return c.aqiStandards
+	}
+
+	epa, _ := aqi.Lookup(aqi.USEPA)
+	standards := []aqi.Standard{epa}
+	country, err := c.countryOf(d)
+	if err != nil {
+		ilog.FromContext(c.ctx).Debug("Failed to get device country.", zap.Stringer("endpoint", d.endpoint), zap.Error(err))
+		return standards
+	}
+	if name := aqi.ForCountry(strings.ToUpper(country)); name != aqi.USEPA {
+		s, _ := aqi.Lookup(name)
+		standards = append(standards, s)
+	}
+	return standards
+}
+
+// countryOf returns the country configured on the device, retrieving it from
+// the device the first time. Failed lookups are retried with an exponential
+// backoff, so that devices without a config endpoint are not queried on every
+// scrape.
+func (c *airgradientCollector) countryOf(d *device) (string, error) {
+	d.mu.Lock()
+	country, lastErr, retry := d.country, d.countryErr, d.countryRetry
+	d.mu.Unlock()
+	if country != nil {
+		return *country, nil
+	}
+	if time.Now().Before(retry) {
+		return "", lastErr
+	}
+
+	cfg, err := c.getDeviceConfig(c.ctx, d)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		d.countryBackoff = min(max(2*d.countryBackoff, countryMinBackoff), countryMaxBackoff)
+		d.countryErr = err
+		d.countryRetry = time.Now().Add(d.countryBackoff)
+		return "", err
+	}
+	d.country = &cfg.Country
+	return cfg.Country, nil
+}
+
+// collectAQI emits the air quality indices of the scraped device computed from
+// its history.
+func (c *airgradientCollector) collectAQI(ch chan<- prometheus.Metric, s *scrape) {
+	samples := s.device.history.since(s.t.Add(-aqiRetention))
+
+	for _, standard := range c.standardsFor(s.device) {
+		for _, averaging := range standard.Averagings() {
+			var dominant aqi.Pollutant
+			var dominantIndex float64
+			var dominantCategory string
+			for _, p := range aqiPollutants {
+				conc, ok := aqiConcentration(averaging, samples, s.t, p.value)
+				if !ok {
+					continue
+				}
+				index, category, ok := standard.Index(p.pollutant, averaging, conc)
+				if !ok {
+					continue
+				}
+				if dominant == "" || index > dominantIndex {
+					dominant, dominantIndex, dominantCategory = p.pollutant, index, category
+				}
+				c.emit(ch, s, c.aqi.pollutant, index, standard.Name(), string(averaging), string(p.pollutant), category)
+			}
+			if dominant != "" {
+				c.emit(ch, s, c.aqi.index, dominantIndex, standard.Name(), string(averaging), string(dominant), dominantCategory)
+			}
+		}
+	}
+}
+
+// aqiConcentration averages the readings using the averaging method.
+func aqiConcentration(averaging aqi.Averaging, samples []sample, now time.Time, value func(m *measures) float64) (float64, bool) {
+	switch averaging {
+	case aqi.AveragingNowCast:
+		return aqi.NowCast(bucketMeans(samples, now, time.Hour, 12, value))
+	case aqi.Averaging1h:
+		mean := bucketMeans(samples, now, time.Hour, 1, value)[0]
+		return mean, !math.IsNaN(mean)
+	case aqi.Averaging24h:
+		var sum float64
+		var n int
+		for _, m := range bucketMeans(samples, now, time.Hour, 24, value) {
+			if !math.IsNaN(m) {
+				sum += m
+				n++
+			}
+		}
+		if n < min24hHours {
+			return 0, false
+		}
+		return sum / float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/baseline"
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/ilog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const baselineGroup = "baseline"
+
+// baselines exports the usual values of device measures for the current hour of
+// the week and how far readings deviate from them.
+type baselines struct {
+	// store is nil unless baselines are configured.
+	store        *baseline.Store
+	sources      []*family
+	saveInterval time.Duration
+
+	median    *family
+	spread    *family
+	deviation *family
+}
+
+func (c *airgradientCollector) newBaselines(cfg *config.Baselines) (*baselines, error) {
+	b := &baselines{
+		median: c.newFamily("baseline_median", baselineGroup, "Median of a measure learned for the current hour of the week", prometheus.GaugeValue, nil, nil,
+			"measure"),
+		spread: c.newFamily("baseline_spread", baselineGroup, "Spread of a measure learned for the current hour of the week, estimating its standard deviation", prometheus.GaugeValue, nil, nil,
+			"measure"),
+		deviation: c.newFamily("baseline_deviation", baselineGroup, "Number of spreads a measure is away from its median for the current hour of the week", prometheus.GaugeValue, nil, nil,
+			"measure"),
+	}
+	if cfg == nil {
+		return b, nil
+	}
+
+	for _, name := range cfg.Measures {
+		f := c.measureFamily(name)
+		if f == nil {
+			return nil, fmt.Errorf("unknown baseline measure %q", name)
+		}
+		b.sources = append(b.sources, f)
+	}
+	var err error
+	if b.store, err = baseline.Open(cfg.Path); err != nil {
+		return nil, err
+	}
+	b.saveInterval = cfg.SaveInterval
+	return b, nil
+}
+
+func (b *baselines) families() []*family {
+	return []*family{b.median, b.spread, b.deviation}
+}
+
+// learnBaselines adds the device's readings taken since its baselines were last
+// updated, except those taken while their sensor warmed up.
+func (c *airgradientCollector) learnBaselines(d *device) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c.replay(d, &d.baselineUpdated, func(s sample, _ time.Duration) {
+		serial := strings.ToLower(s.m.SerialNo)
+		for _, f := range c.baselines.sources {
+			if !c.warmingUp(s.m, f) {
+				c.baselines.store.Add(serial, f.name, s.t, f.value(s.m))
+			}
+		}
+	})
+}
+
+// collectBaselines emits the learned baselines of the scraped device and the
+// deviation of its readings.
+func (c *airgradientCollector) collectBaselines(ch chan<- prometheus.Metric, s *scrape) {
+	if c.baselines.store == nil {
+		return
+	}
+	c.learnBaselines(s.device)
+
+	serial := strings.ToLower(s.measures.SerialNo)
+	for _, f := range c.baselines.sources {
+		if !s.caps.supports(f.sensors...) || s.suppressed(f) {
+			continue
+		}
+		median, spread, ok := c.baselines.store.Stats(serial, f.name, s.t)
+		if !ok {
+			continue
+		}
+		c.emit(ch, s, c.baselines.median, median, f.name)
+		c.emit(ch, s, c.baselines.spread, spread, f.name)
+		if z, ok := baseline.Deviation(f.value(s.measures), median, spread); ok {
+			c.emit(ch, s, c.baselines.deviation, z, f.name)
+		}
+	}
+}
+
+// saveBaselines persists the baselines at the save interval until the context is
+// done, then persists them one last time.
+func (c *airgradientCollector) saveBaselines(ctx context.Context) {
+	ticker := time.NewTicker(c.baselines.saveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := c.baselines.store.Save(); err != nil {
+				ilog.FromContext(ctx).Error("Failed to save baselines.", zap.Error(err))
+			}
+			return
+		case <-ticker.C:
+			if err := c.baselines.store.Save(); err != nil {
+				ilog.FromContext(ctx).Error("Failed to save baselines.", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/correction"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// calibratedFamily exports a measure corrected by the device calibration.
+type calibratedFamily struct {
+	family *family
+	source *family
+	// coefficients returns the calibration polynomial of the measure.
+	coefficients func(c config.Calibration) []float64
+}
+
+// calibrations applies the configured per-device calibrations.
+type calibrations struct {
+	bySerial map[string]config.Calibration
+	info     *family
+	families []calibratedFamily
+}
+
+func (c *airgradientCollector) newCalibrations(cfg map[string]config.Calibration) *calibrations {
+	cal := &calibrations{
+		bySerial: make(map[string]config.Calibration, len(cfg)),
+		info: c.newFamily("calibration_info", "device", "Calibration applied to the device", prometheus.GaugeValue, nil, nil,
+			"version"),
+	}
+	for serial, calibration := range cfg {
+		cal.bySerial[strings.ToLower(serial)] = calibration
+	}
+
+	channels := []struct {
+		source       string
+		help         string
+		coefficients func(c config.Calibration) []float64
+	}{
+		{"atmp", "Temperature in Degrees Celsius with the device calibration applied",
+			func(c config.Calibration) []float64 { return c.Temperature }},
+		{"rhum", "Relative Humidity with the device calibration applied",
+			func(c config.Calibration) []float64 { return c.Humidity }},
+		{"rco2", "CO2 in ppm with the device calibration applied",
+			func(c config.Calibration) []float64 { return c.CO2 }},
+		{"pm02", "PM2.5 in ug/m3 with the device calibration applied",
+			func(c config.Calibration) []float64 { return c.PM25 }},
+	}
+	for _, ch := range channels {
+		for _, f := range c.measureFamilies {
+			if f.name != ch.source {
+				continue
+			}
+			cal.families = append(cal.families, calibratedFamily{
+				family:       c.newFamily(f.name+"_calibrated", f.group, ch.help, f.valueType, f.sensors, nil),
+				source:       f,
+				coefficients: ch.coefficients,
+			})
+		}
+	}
+	return cal
+}
+
+func (cal *calibrations) all() []*family {
+	families := []*family{cal.info}
+	for _, f := range cal.families {
+		families = append(families, f.family)
+	}
+	return families
+}
+
+// collectCalibrated emits the calibrated measures of the scraped device if it
+// has a calibration.
+func (c *airgradientCollector) collectCalibrated(ch chan<- prometheus.Metric, s *scrape) {
+	calibration, ok := c.calibrations.bySerial[strings.ToLower(s.measures.SerialNo)]
+	if !ok {
+		return
+	}
+	c.emit(ch, s, c.calibrations.info, 1, calibration.Version)
+	for _, f := range c.calibrations.families {
+		coefficients := f.coefficients(calibration)
+		if len(coefficients) == 0 {
+			continue
+		}
+		c.emit(ch, s, f.family, correction.Polynomial(coefficients).Apply(f.source.value(s.measures)))
+	}
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"github.com/dtrejod/airgradient-exporter/internal/comfort"
+	"github.com/dtrejod/airgradient-exporter/internal/ilog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const comfortGroup = "comfort"
+
+// comfortFamilies exports the ISO 7730 thermal comfort of a device's room.
+type comfortFamilies struct {
+	pmv *family
+	ppd *family
+}
+
+func (c *airgradientCollector) newComfortFamilies() *comfortFamilies {
+	return &comfortFamilies{
+		pmv: c.newFamily("comfort_pmv", comfortGroup, "ISO 7730 Predicted Mean Vote", prometheus.GaugeValue, temperatureSensors, nil,
+			"source"),
+		ppd: c.newFamily("comfort_ppd", comfortGroup, "ISO 7730 Predicted Percentage of Dissatisfied in percent", prometheus.GaugeValue, temperatureSensors, nil,
+			"source"),
+	}
+}
+
+func (f *comfortFamilies) families() []*family {
+	return []*family{f.pmv, f.ppd}
+}
+
+// collectComfort emits the thermal comfort computed from the raw and
+// compensated readings of the scraped device, if configured. The mean radiant
+// temperature is approximated by the air temperature.
+func (c *airgradientCollector) collectComfort(ch chan<- prometheus.Metric, s *scrape) {
+	cfg := s.device.comfort
+	if cfg == nil {
+		return
+	}
+
+	for _, in := range psychroInputs {
+		t, rh := in.t(s.measures), in.rh(s.measures)
+		if rh <= 0 || rh > 100 {
+			continue
+		}
+		pmv, err := comfort.PMV(comfort.Conditions{
+			AirTemperature:     t,
+			RadiantTemperature: t,
+			AirSpeed:           *cfg.AirSpeed,
+			RelativeHumidity:   rh,
+			MetabolicRate:      *cfg.MetabolicRate,
+			Clothing:           *cfg.Clothing,
+		})
+		if err != nil {
+			ilog.FromContext(c.ctx).Debug("Failed to compute thermal comfort.", zap.String("source", in.source), zap.Error(err))
+			continue
+		}
+		c.emit(ch, s, c.comfort.pmv, pmv, in.source)
+		c.emit(ch, s, c.comfort.ppd, comfort.PPD(pmv), in.source)
+	}
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/expr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const derivedGroup = "derived"
+
+var derivedNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// derivedMetric is a metric computed from the device measures and the derived
+// metrics defined before it.
+type derivedMetric struct {
+	family *family
+	expr   *expr.Expr
+	// measures maps the measures the metric is derived from to their family.
+	measures map[string]*family
+}
+
+// newDerived creates the derived metrics. It must be called once all other
+// families are created so that name clashes are detected.
+func (c *airgradientCollector) newDerived(metrics []config.DerivedMetric) ([]derivedMetric, error) {
+	taken := make(map[string]bool)
+	for _, f := range c.allFamilies() {
+		taken[f.name] = true
+	}
+
+	measures := make(map[string]*family, len(c.measureFamilies))
+	for _, f := range c.measureFamilies {
+		measures[f.name] = f
+	}
+
+	var derived []derivedMetric
+	// defined holds the sensors of the derived metrics defined so far.
+	defined := make(map[string][]sensor)
+	// known lists the identifiers expressions may reference.
+	known := c.measureNames()
+	for _, m := range metrics {
+		if !derivedNameRE.MatchString(m.Name) {
+			return nil, fmt.Errorf("invalid derived metric name %q", m.Name)
+		}
+		if taken[m.Name] {
+			return nil, fmt.Errorf("invalid derived metric %q: name is already exported", m.Name)
+		}
+		e, err := expr.Parse(m.Expression, known)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derived metric %q: %w", m.Name, err)
+		}
+		// The metric is exported if the device has a sensor reporting any of
+		// the values it is derived from, and is suppressed while they all
+		// warm up.
+		var sensors []sensor
+		inputs := make(map[string]*family)
+		for _, ident := range e.Identifiers() {
+			if f, ok := measures[ident]; ok {
+				sensors = appendSensors(sensors, f.sensors)
+				inputs[ident] = f
+			} else {
+				sensors = appendSensors(sensors, defined[ident])
+			}
+		}
+
+		help := m.Help
+		if help == "" {
+			help = "Derived from " + m.Expression
+		}
+		derived = append(derived, derivedMetric{
+			family:   c.newFamily(m.Name, derivedGroup, help, prometheus.GaugeValue, sensors, nil),
+			expr:     e,
+			measures: inputs,
+		})
+		taken[m.Name], defined[m.Name] = true, sensors
+		known = append(known, m.Name)
+	}
+	return derived, nil
+}
+
+// appendSensors adds the sensors missing from the list.
+func appendSensors(list, sensors []sensor) []sensor {
+	for _, s := range sensors {
+		if !slices.Contains(list, s) {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// measureNames returns the sorted names of the measure families.
+func (c *airgradientCollector) measureNames() []string {
+	names := make([]string, 0, len(c.measureFamilies))
+	for _, f := range c.measureFamilies {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func derivedFamilies(derived []derivedMetric) []*family {
+	families := make([]*family, len(derived))
+	for i, d := range derived {
+		families[i] = d.family
+	}
+	return families
+}
+
+// collectDerived evaluates the derived metrics in order for the scraped device.
+// Metrics evaluating to NaN or infinity, e.g. on division by zero, are not
+// exported and evaluate to NaN in the metrics defined after them.
+func (c *airgradientCollector) collectDerived(ch chan<- prometheus.Metric, s *scrape) {
+	values := make(map[string]float64, len(c.derived))
+	for _, d := range c.derived {
+		v := d.expr.Eval(func(ident string) float64 {
+			if f, ok := d.measures[ident]; ok {
+				return f.value(s.measures)
+			}
+			return values[ident]
+		})
+		if math.IsInf(v, 0) {
+			v = math.NaN()
+		}
+		values[d.family.name] = v
+		if !math.IsNaN(v) {
+			c.emit(ch, s, d.family, v)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package collector
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Types of air events.
+const (
+	eventCooking          = "cooking"
+	eventSmoke            = "smoke"
+	eventDust             = "dust"
+	eventCleaningProducts = "cleaning_products"
+	eventOccupancy        = "occupancy"
+)
+
+var eventTypes = []string{eventCooking, eventSmoke, eventDust, eventCleaningProducts, eventOccupancy}
+
+const (
+	// eventBaselineTau is the time constant of the baseline events rise above.
+	eventBaselineTau = time.Hour
+	// fineRatio is the PM2.5 to PM10 ratio above which particles are fine, as
+	// produced by combustion rather than dust.
+	fineRatio = 0.7
+	// maxRecentEvents is the number of events served by the events endpoint.
+	maxRecentEvents = 100
+)
+
+// eventSignal is a measure watched for events.
+type eventSignal struct {
+	measure string
+	value   func(m *measures) float64
+	// rise is the rise above the baseline starting an event. The event ends
+	// once the measure falls below half of the rise.
+	rise float64
+	// minDuration is the shortest event that is recorded.
+	minDuration time.Duration
+	// classify returns the type of an ended event, or an empty string if it is
+	// not recorded.
+	classify func(st *signalState) string
+}
+
+var eventSignals = []eventSignal{
+	{
+		measure: "pm02", rise: 15,
+		value: func(m *measures) float64 { return float64(m.PM02) },
+		classify: func(st *signalState) string {
+			switch {
+			case st.peakRatio < fineRatio:
+				return eventDust
+			case st.overlap:
+				return eventCooking
+			}
+			return eventSmoke
+		},
+	},
+	{
+		measure: "tvoc_index", rise: 100,
+		value: func(m *measures) float64 { return float64(m.TVOCIndex) },
+		classify: func(st *signalState) string {
+			// VOC surges with particles are part of a cooking event.
+			if st.overlap {
+				return ""
+			}
+			return eventCleaningProducts
+		},
+	},
+	{
+		measure: "rco2", rise: 400, minDuration: 15 * time.Minute,
+		value:    func(m *measures) float64 { return float64(m.RCO2) },
+		classify: func(*signalState) string { return eventOccupancy },
+	},
+}
+
+// The particle and VOC signals are correlated to classify cooking.
+const (
+	pmSignal  = 0
+	vocSignal = 1
+)
+
+// airEvent is a detected pollution event.
+type airEvent struct {
+	SerialNo        string    `json:"serialno"`
+	Type            string    `json:"type"`
+	Measure         string    `json:"measure"`
+	Start           time.Time `json:"start"`
+	PeakTime        time.Time `json:"peak_time"`
+	Peak            float64   `json:"peak"`
+	End             time.Time `json:"end"`
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+// signalState tracks a signal of a device.
+type signalState struct {
+	// seen is whether the baseline was initialized from a reading.
+	seen      bool
+	baseline  float64
+	active    bool
+	start     time.Time
+	peak      float64
+	peakTime  time.Time
+	peakRatio float64
+	// overlap is whether the correlated signal had an event at the same time.
+	overlap bool
+}
+
+// eventState holds the event detection of a device, updated with the readings up
+// to updated.
+type eventState struct {
+	updated time.Time
+	signals []signalState
+	counts  map[string]float64
+}
+
+// events detects air events and keeps the most recent ones.
+type events struct {
+	// sources are the measure families of the signals.
+	sources []*family
+	family  *family
+
+	mu     sync.Mutex
+	recent []airEvent
+}
+
+func (c *airgradientCollector) newEvents() *events {
+	e := &events{
+		family: c.newFamily("air_events_total", "events", "Number of air events detected by type", prometheus.CounterValue, nil, nil,
+			"type"),
+	}
+	for _, sig := range eventSignals {
+		e.sources = append(e.sources, c.measureFamily(sig.measure))
+	}
+	return e
+}
+
+func (e *events) record(ev airEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recent = append(e.recent, ev)
+	if len(e.recent) > maxRecentEvents {
+		e.recent = e.recent[len(e.recent)-maxRecentEvents:]
+	}
+}
+
+// detectEvents runs event detection on the device's readings taken since it was
+// last updated and returns a copy of the event counts.
+func (c *airgradientCollector) detectEvents(d *device) map[string]float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := &d.events
+	if st.signals == nil {
+		st.signals = make([]signalState, len(eventSignals))
+		st.counts = make(map[string]float64, len(eventTypes))
+	}
+
+	c.replay(d, &st.updated, func(s sample, held time.Duration) {
+		for i, sig := range eventSignals {
+			sst := &st.signals[i]
+			switch {
+			case c.warmingUp(s.m, c.events.sources[i]):
+				// Start over from the first reading after the sensor warmed
+				// up.
+				*sst = signalState{}
+			case !sst.seen:
+				*sst = signalState{seen: true, baseline: sig.value(s.m)}
+			default:
+				if ev, ok := sst.update(sig, s, held); ok {
+					st.counts[ev.Type]++
+					c.events.record(ev)
+				}
+			}
+		}
+		if st.signals[pmSignal].active && st.signals[vocSignal].active {
+			st.signals[pmSignal].overlap = true
+			st.signals[vocSignal].overlap = true
+		}
+	})
+
+	counts := make(map[string]float64, len(st.counts))
+	for t, n := range st.counts {
+		counts[t] = n
+	}
+	return counts
+}
+
+// update advances the signal with a reading held for the duration since the
+// previous one, returning the event that ended with it, if recorded.
+func (st *signalState) update(sig eventSignal, s sample, held time.Duration) (airEvent, bool) {
+	v := sig.value(s.m)
+	if !st.active {
+		if v <= st.baseline+sig.rise {
+			st.baseline += (v - st.baseline) * (1 - math.Exp(-held.Seconds()/eventBaselineTau.Seconds()))
+			return airEvent{}, false
+		}
+		*st = signalState{seen: true, baseline: st.baseline, active: true, start: s.t}
+	}
+
+	if v > st.peak {
+		st.peak, st.peakTime = v, s.t
+		st.peakRatio = 1
+		if s.m.PM10 > 0 {
+			st.peakRatio = float64(s.m.PM02) / float64(s.m.PM10)
+		}
+	}
+	if v >= st.baseline+sig.rise/2 {
+		return airEvent{}, false
+	}
+
+	st.active = false
+	duration := s.t.Sub(st.start)
+	if duration < sig.minDuration {
+		return airEvent{}, false
+	}
+	typ := sig.classify(st)
+	if typ == "" {
+		return airEvent{}, false
+	}
+	return airEvent{
+		SerialNo:        s.m.SerialNo,
+		Type:            typ,
+		Measure:         sig.measure,
+		Start:           st.start,
+		PeakTime:        st.peakTime,
+		Peak:            st.peak,
+		End:             s.t,
+		DurationSeconds: duration.Seconds(),
+	}, true
+}
+
+// collectEvents emits the number of air events detected on the scraped device.
+func (c *airgradientCollector) collectEvents(ch chan<- prometheus.Metric, s *scrape) {
+	counts := c.detectEvents(s.device)
+	for _, t := range eventTypes {
+		c.emit(ch, s, c.events.family, counts[t], t)
+	}
+}
+
+// serve writes the recent air events as JSON, most recent first, with serial
+// numbers relabeled. The events of a single device are selected with the serialno
+// query parameter.
+func (e *events) serve(w http.ResponseWriter, r *http.Request, rl *relabeler) {
+	serial := r.URL.Query().Get(serialLabel)
+
+	e.mu.Lock()
+	recent := make([]airEvent, 0, len(e.recent))
+	for i := len(e.recent) - 1; i >= 0; i-- {
+		ev := e.recent[i]
+		ev.SerialNo = rl.serial(ev.SerialNo)
+		if serial == "" || strings.EqualFold(ev.SerialNo, serial) {
+			recent = append(recent, ev)
+		}
+	}
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(recent); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
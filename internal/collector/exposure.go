@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const exposureGroup = "exposure"
+
+// threshold counts the time a measure spends above a value.
+type threshold struct {
+	source *family
+	above  float64
+	// label is the formatted value of the threshold.
+	label string
+}
+
+// exposure exports counters of the time measures spend above thresholds and of
+// their time-integrated dose.
+type exposure struct {
+	thresholds []threshold
+	doses      []*family
+
+	timeAbove *family
+	dose      *family
+}
+
+// exposureState holds the counters of a device, accumulated from its readings up
+// to updated.
+type exposureState struct {
+	updated time.Time
+	// last is the reading taken at updated.
+	last      *measures
+	timeAbove []float64
+	dose      []float64
+}
+
+func (c *airgradientCollector) newExposure(thresholds []config.Threshold, doses []string) (*exposure, error) {
+	e := &exposure{
+		timeAbove: c.newFamily("time_above_threshold_seconds_total", exposureGroup, "Time a measure spent above a threshold in seconds", prometheus.CounterValue, nil, nil,
+			"measure", "threshold"),
+		dose: c.newFamily("dose_total", exposureGroup, "Time-integrated measure in its unit times seconds, e.g. ug/m3*s", prometheus.CounterValue, nil, nil,
+			"measure"),
+	}
+	for _, t := range thresholds {
+		f := c.measureFamily(t.Measure)
+		if f == nil {
+			return nil, fmt.Errorf("unknown threshold measure %q", t.Measure)
+		}
+		e.thresholds = append(e.thresholds, threshold{source: f, above: t.Above, label: strconv.FormatFloat(t.Above, 'g', -1, 64)})
+	}
+	for _, name := range doses {
+		f := c.measureFamily(name)
+		if f == nil {
+			return nil, fmt.Errorf("unknown dose measure %q", name)
+		}
+		e.doses = append(e.doses, f)
+	}
+	return e, nil
+}
+
+func (e *exposure) families() []*family {
+	return []*family{e.timeAbove, e.dose}
+}
+
+// measureFamily returns the measure family with the name, or nil if there is
+// none.
+func (c *airgradientCollector) measureFamily(name string) *family {
+	for _, f := range c.measureFamilies {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// exposureCounters accumulates the device's readings taken since the counters
+// were last updated and returns a copy of the counters. Each reading counts for
+// the time it held until the next one, unless taken while its sensor warmed up.
+func (c *airgradientCollector) exposureCounters(d *device) (timeAbove, dose []float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := &d.exposure
+	if st.timeAbove == nil {
+		st.timeAbove = make([]float64, len(c.exposure.thresholds))
+		st.dose = make([]float64, len(c.exposure.doses))
+	}
+
+	c.replay(d, &st.updated, func(s sample, held time.Duration) {
+		if st.last != nil {
+			for i, t := range c.exposure.thresholds {
+				if !c.warmingUp(st.last, t.source) && t.source.value(st.last) > t.above {
+					st.timeAbove[i] += held.Seconds()
+				}
+			}
+			for i, f := range c.exposure.doses {
+				if !c.warmingUp(st.last, f) {
+					st.dose[i] += f.value(st.last) * held.Seconds()
+				}
+			}
+		}
+		st.last = s.m
+	})
+	return append([]float64(nil), st.timeAbove...), append([]float64(nil), st.dose...)
+}
+
+// collectExposure emits the exposure counters of the scraped device.
+func (c *airgradientCollector) collectExposure(ch chan<- prometheus.Metric, s *scrape) {
+	timeAbove, dose := c.exposureCounters(s.device)
+	for i, t := range c.exposure.thresholds {
+		if s.caps.supports(t.source.sensors...) && !s.suppressed(t.source) {
+			c.emit(ch, s, c.exposure.timeAbove, timeAbove[i], t.source.name, t.label)
+		}
+	}
+	for i, f := range c.exposure.doses {
+		if s.caps.supports(f.sensors...) && !s.suppressed(f) {
+			c.emit(ch, s, c.exposure.dose, dose[i], f.name)
+		}
+	}
+}
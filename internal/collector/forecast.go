@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/forecast"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	forecastGroup = "forecast"
+	// forecastStep is the period readings are averaged over before fitting.
+	forecastStep = time.Minute
+	// forecastLookback is the history the model is fitted to.
+	forecastLookback = time.Hour
+)
+
+// forecastHorizons are how far ahead measures are forecast.
+var forecastHorizons = []time.Duration{15 * time.Minute, 30 * time.Minute}
+
+// forecastModel smooths recent minute averages, requiring 10 minutes of them.
+var forecastModel = forecast.Holt{Alpha: 0.3, Beta: 0.1, Phi: 0.98, MinValues: 10}
+
+// forecastFamilies lists the measure families forecast.
+var forecastFamilies = []string{"pm02", "rco2"}
+
+// forecasts exports short-term forecasts of device measures.
+type forecasts struct {
+	sources []*family
+	family  *family
+}
+
+func (c *airgradientCollector) newForecasts() *forecasts {
+	f := &forecasts{
+		family: c.newFamily("forecast", forecastGroup, "Measure forecast at a horizon from the readings of the last hour", prometheus.GaugeValue, nil, nil,
+			"measure", "horizon"),
+	}
+	for _, name := range forecastFamilies {
+		f.sources = append(f.sources, c.measureFamily(name))
+	}
+	return f
+}
+
+// collectForecasts emits the forecasts of the scraped device, projected from the
+// time of the scrape. Devices whose latest reading no longer holds are not
+// forecast.
+func (c *airgradientCollector) collectForecasts(ch chan<- prometheus.Metric, s *scrape) {
+	steps := int(forecastLookback / forecastStep)
+	samples := s.device.history.since(s.t.Add(-forecastLookback))
+	if len(samples) == 0 || s.t.Sub(samples[len(samples)-1].t) > c.maxHold {
+		return
+	}
+	for _, f := range c.forecasts.sources {
+		if !s.caps.supports(f.sensors...) || s.suppressed(f) {
+			continue
+		}
+		values := bucketMeans(samples, s.t, forecastStep, steps, f.value)
+		slices.Reverse(values)
+		for _, h := range forecastHorizons {
+			v, err := forecastModel.Forecast(values, int(h/forecastStep))
+			if err != nil {
+				break
+			}
+			c.emit(ch, s, c.forecasts.family, math.Max(v, 0), f.name, formatWindow(h))
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/ilog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const collectParam = "collect[]"
+
+// NewHandler creates a Handler serving the metrics of the configured devices.
+// Requests may narrow the exported metric families with one or more collect[]
+// query parameters, e.g. /metrics?collect[]=pm&collect[]=co2. The collector is
+// registered with the default Prometheus registry, which is served when no
+// collect[] parameters are provided. If a poll interval is configured, devices
+// are polled in the background until the context is done. Learned baselines are
+// likewise persisted in the background, and once more when the context is done.
+func NewHandler(ctx context.Context, cfg config.Config) (*Handler, error) {
+	c, err := newAirGradient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := prometheus.Register(c); err != nil {
+		return nil, fmt.Errorf("failed to register collector: %w", err)
+	}
+
+	h := &Handler{
+		ctx:        ctx,
+		collector:  c,
+		unfiltered: promhttp.Handler(),
+	}
+	if c.pollInterval > 0 {
+		h.background.Add(1)
+		go func() {
+			defer h.background.Done()
+			c.run(ctx)
+		}()
+	}
+	if c.baselines.store != nil {
+		h.background.Add(1)
+		go func() {
+			defer h.background.Done()
+			c.saveBaselines(ctx)
+		}()
+	}
+	return h, nil
+}
+
+// Handler serves the metrics of the configured devices.
+type Handler struct {
+	ctx        context.Context
+	collector  *airgradientCollector
+	unfiltered http.Handler
+	// background tracks the polling and saving of baselines.
+	background sync.WaitGroup
+}
+
+// Wait blocks until the background work stops after the context is done,
+// including the last save of the baselines.
+func (h *Handler) Wait() {
+	h.background.Wait()
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filters := r.URL.Query()[collectParam]
+	if len(filters) == 0 {
+		h.unfiltered.ServeHTTP(w, r)
+		return
+	}
+
+	if err := h.collector.validateSelectors(filters); err != nil {
+		ilog.FromContext(h.ctx).Debug("Rejected metrics request.", zap.Strings("collect", filters), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(h.collector.filtered(selector{include: filters})); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// Events returns a http.Handler serving the recent air events detected on the
+// configured devices as JSON.
+func (h *Handler) Events() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.collector.events.serve(w, r, h.collector.relabeler)
+	})
+}
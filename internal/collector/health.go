@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons a sensor is reported unhealthy.
+const (
+	healthStuck      = "stuck"
+	healthOutOfRange = "out_of_range"
+	healthJump       = "jump"
+)
+
+// jumpHold is how long a sensor is reported as jumping after an impossible
+// change between readings.
+const jumpHold = 15 * time.Minute
+
+// healthCheck watches a measure for readings that indicate a failing sensor.
+type healthCheck struct {
+	measure string
+	// sensors that may report the measure, the first fitted one is blamed.
+	sensors []sensor
+	// min and max are the physically plausible readings.
+	min, max float64
+	// maxJump is the largest plausible change between consecutive readings,
+	// or zero if jumps are not checked.
+	maxJump float64
+	// stuck is whether the measure is noisy enough to never hold the same
+	// value for long on a working sensor.
+	stuck bool
+}
+
+var healthChecks = []healthCheck{
+	{measure: "pm02", sensors: pmSensors, min: 0, max: 1000, maxJump: 500},
+	{measure: "pm003_count", sensors: pmSensors, min: 0, max: 65535, stuck: true},
+	{measure: "rco2", sensors: co2Sensors, min: 250, max: 10000, maxJump: 2000, stuck: true},
+	{measure: "atmp", sensors: []sensor{sensorSHT, sensorPMS5003T}, min: -40, max: 85, maxJump: 10, stuck: true},
+	{measure: "rhum", sensors: []sensor{sensorSHT, sensorPMS5003T}, min: 1, max: 100, maxJump: 40},
+	{measure: "tvoc_raw", sensors: vocSensors, min: 0, max: 65535, stuck: true},
+	{measure: "nox_raw", sensors: vocSensors, min: 0, max: 65535, stuck: true},
+}
+
+// sensorHealth diagnoses failing sensors from their readings.
+type sensorHealth struct {
+	checks []healthCheck
+	// sources are the measure families of the checks.
+	sources []*family
+	// stuckReadings is the number of identical consecutive readings after
+	// which a sensor is stuck.
+	stuckReadings int
+
+	family *family
+}
+
+// measureHealth holds the state of a health check of a device.
+type measureHealth struct {
+	// seen is whether last holds a reading to compare the next one with.
+	seen      bool
+	last      float64
+	unchanged int
+	jumped    time.Time
+}
+
+// healthState holds the health checks of a device, updated with the readings up
+// to updated.
+type healthState struct {
+	updated  time.Time
+	measures []measureHealth
+}
+
+func (c *airgradientCollector) newSensorHealth(stuckReadings int) *sensorHealth {
+	h := &sensorHealth{
+		checks:        healthChecks,
+		stuckReadings: stuckReadings,
+		family: c.newFamily("sensor_health", "device", "Whether a sensor issue is detected, by reason", prometheus.GaugeValue, nil, nil,
+			"sensor", "reason"),
+	}
+	for _, check := range h.checks {
+		h.sources = append(h.sources, c.measureFamily(check.measure))
+	}
+	return h
+}
+
+// blame returns the sensor reporting the measure of the check.
+func (check healthCheck) blame(caps capabilities) (sensor, bool) {
+	for _, s := range check.sensors {
+		if caps == nil || caps[s] {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// updateHealth runs the health checks on the device's readings taken since they
+// were last updated and returns a copy of the check states.
+func (c *airgradientCollector) updateHealth(d *device) []measureHealth {
+	h := c.sensorHealth
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := &d.health
+	if st.measures == nil {
+		st.measures = make([]measureHealth, len(h.checks))
+	}
+
+	c.replay(d, &st.updated, func(s sample, held time.Duration) {
+		for i, check := range h.checks {
+			mh := &st.measures[i]
+			if c.warmingUp(s.m, h.sources[i]) {
+				// Warm-up readings are neither checked nor compared with.
+				*mh = measureHealth{jumped: mh.jumped}
+				continue
+			}
+			v := h.sources[i].value(s.m)
+			if mh.seen {
+				if v == mh.last {
+					mh.unchanged++
+				} else {
+					mh.unchanged = 0
+				}
+				// st.updated is still the time of the previous reading.
+				if check.maxJump > 0 && s.t.Sub(st.updated) <= c.maxHold && math.Abs(v-mh.last) > check.maxJump {
+					mh.jumped = s.t
+				}
+			}
+			mh.seen, mh.last = true, v
+		}
+	})
+	return append([]measureHealth(nil), st.measures...)
+}
+
+// collectSensorHealth emits the issues detected on each sensor of the scraped
+// device. A sensor has an issue if any of the measures it reports does.
+func (c *airgradientCollector) collectSensorHealth(ch chan<- prometheus.Metric, s *scrape) {
+	h := c.sensorHealth
+	states := c.updateHealth(s.device)
+
+	issues := make(map[sensor]map[string]bool)
+	var sensors []sensor
+	for i, check := range h.checks {
+		blamed, ok := check.blame(s.caps)
+		if !ok {
+			continue
+		}
+		if issues[blamed] == nil {
+			issues[blamed] = make(map[string]bool)
+			sensors = append(sensors, blamed)
+		}
+		if s.suppressed(h.sources[i]) {
+			continue
+		}
+		v, st := h.sources[i].value(s.measures), states[i]
+		if check.stuck && st.unchanged+1 >= h.stuckReadings {
+			issues[blamed][healthStuck] = true
+		}
+		if v < check.min || v > check.max {
+			issues[blamed][healthOutOfRange] = true
+		}
+		if !st.jumped.IsZero() && s.t.Sub(st.jumped) < jumpHold {
+			issues[blamed][healthJump] = true
+		}
+	}
+
+	for _, sensor := range sensors {
+		for _, reason := range []string{healthStuck, healthOutOfRange, healthJump} {
+			c.emit(ch, s, h.family, boolValue(issues[sensor][reason]), string(sensor), reason)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// defaultMaxHold is the longest time a reading is assumed to hold until the next
+// one when devices are polled at most every couple of minutes, so that gaps in
+// the history are not filled in.
+const defaultMaxHold = 5 * time.Minute
+
+// sample is a reading of a device at a point in time.
+type sample struct {
+	t time.Time
+	m *measures
+}
+
+// history is a time ordered buffer of the recent readings of a device.
+type history struct {
+	retention time.Duration
+
+	mu      sync.Mutex
+	samples []sample
+}
+
+func newHistory(retention time.Duration) *history {
+	return &history{retention: retention}
+}
+
+// add appends a reading and drops those older than the retention.
+func (h *history) add(t time.Time, m *measures) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = append(h.samples, sample{t: t, m: m})
+	cutoff := t.Add(-h.retention)
+	i := 0
+	for i < len(h.samples) && h.samples[i].t.Before(cutoff) {
+		i++
+	}
+	h.samples = h.samples[i:]
+}
+
+// since returns the readings taken after t, oldest first.
+func (h *history) since(t time.Time) []sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i := len(h.samples)
+	for i > 0 && h.samples[i-1].t.After(t) {
+		i--
+	}
+	return append([]sample(nil), h.samples[i:]...)
+}
+
+// replay calls fn with each of the device's readings taken after updated, oldest
+// first, and advances updated to the latest of them. held is how long the
+// previous reading held until the reading, capped at the collector's maxHold, or
+// zero for the first reading replayed since updated was zero. The device must
+// be locked.
+func (c *airgradientCollector) replay(d *device, updated *time.Time, fn func(s sample, held time.Duration)) {
+	for _, s := range d.history.since(*updated) {
+		var held time.Duration
+		if !updated.IsZero() {
+			held = min(s.t.Sub(*updated), c.maxHold)
+		}
+		fn(s, held)
+		*updated = s.t
+	}
+}
+
+// latest returns the most recent reading.
+func (h *history) latest() (sample, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) == 0 {
+		return sample{}, false
+	}
+	return h.samples[len(h.samples)-1], true
+}
+
+// alignSamples pairs the readings of two devices taken within the tolerance of
+// each other. Both readings must be ordered oldest first.
+func alignSamples(a, b []sample, tolerance time.Duration) [][2]*measures {
+	var pairs [][2]*measures
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		d := a[i].t.Sub(b[j].t)
+		switch {
+		case d > tolerance:
+			j++
+		case d < -tolerance:
+			i++
+		default:
+			pairs = append(pairs, [2]*measures{a[i].m, b[j].m})
+			i++
+			j++
+		}
+	}
+	return pairs
+}
+
+// bucketMeans averages the value of the readings taken within each of the n
+// periods preceding now, most recent first. Periods without readings are NaN.
+func bucketMeans(samples []sample, now time.Time, period time.Duration, n int, value func(m *measures) float64) []float64 {
+	sums := make([]float64, n)
+	counts := make([]int, n)
+	for _, s := range samples {
+		age := now.Sub(s.t)
+		if age < 0 {
+			continue
+		}
+		i := int(age / period)
+		if i >= n {
+			continue
+		}
+		sums[i] += value(s.m)
+		counts[i]++
+	}
+	means := make([]float64, n)
+	for i := range means {
+		means[i] = math.NaN()
+		if counts[i] > 0 {
+			means[i] = sums[i] / float64(counts[i])
+		}
+	}
+	return means
+}
@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/infection"
+	"github.com/dtrejod/airgradient-exporter/internal/occupancy"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const infectionGroup = "infection_risk"
+
+// infectionFamilies exports the airborne infection risk of a device's room.
+type infectionFamilies struct {
+	rebreathed  *family
+	probability *family
+}
+
+func (c *airgradientCollector) newInfectionFamilies() *infectionFamilies {
+	return &infectionFamilies{
+		rebreathed:  c.newFamily("rebreathed_fraction", infectionGroup, "Fraction of inhaled air exhaled by other occupants", prometheus.GaugeValue, co2Sensors, nil),
+		probability: c.newFamily("infection_risk", infectionGroup, "Wells-Riley probability of infection of a susceptible occupant over the configured exposure", prometheus.GaugeValue, co2Sensors, nil),
+	}
+}
+
+func (f *infectionFamilies) families() []*family {
+	return []*family{f.rebreathed, f.probability}
+}
+
+// collectInfectionRisk emits the rebreathed fraction and the Wells-Riley
+// infection risk of the scraped device's room, if configured. The risk requires
+// the number of occupants, either configured or estimated.
+func (c *airgradientCollector) collectInfectionRisk(ch chan<- prometheus.Metric, s *scrape) {
+	cfg := s.device.infection
+	if cfg == nil || s.device.environment() == config.EnvironmentOutdoor || !s.caps.supports(co2Sensors...) {
+		return
+	}
+
+	f := infection.RebreathedFraction(float64(s.measures.RCO2), c.outdoorCO2(s.device, s.t), cfg.ExhaledCO2)
+	c.emit(ch, s, c.infection.rebreathed, f)
+
+	occupants := cfg.Occupants
+	if occupants == 0 {
+		in, ok := c.occupancyInputs(s)
+		if !ok {
+			return
+		}
+		occupants = occupancy.SteadyState(in).Occupants
+	}
+	if occupants < 1 || occupants < cfg.Infectors {
+		return
+	}
+	c.emit(ch, s, c.infection.probability, infection.Probability(f, cfg.Infectors, occupants, cfg.Quanta, cfg.Exposure))
+}
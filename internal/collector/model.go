@@ -0,0 +1,100 @@
+package collector
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+)
+
+// sensor identifies a sensor module fitted to an AirGradient board.
+type sensor string
+
+const (
+	sensorPMS5003  sensor = "pms5003"
+	sensorPMS5003T sensor = "pms5003t"
+	sensorS8       sensor = "s8"
+	sensorSGP41    sensor = "sgp41"
+	sensorSHT      sensor = "sht"
+)
+
+// knownModels lists the sensors fitted to models whose name does not follow the
+// Open Air letter scheme handled by parseModel.
+var knownModels = map[string][]sensor{
+	"I-9PSL":        {sensorPMS5003, sensorS8, sensorSGP41, sensorSHT},
+	"DIY-BASIC":     {sensorPMS5003, sensorS8, sensorSHT},
+	"DIY-PRO-I-3.7": {sensorPMS5003, sensorS8, sensorSHT},
+	"DIY-PRO-I-4.2": {sensorPMS5003, sensorS8, sensorSGP41, sensorSHT},
+}
+
+// openAirModel matches Open Air model strings such as O-1PST, where each letter
+// after the revision digit names a fitted sensor.
+var openAirModel = regexp.MustCompile(`^O-\d+([A-Z]+)`)
+
+// capabilities is the set of sensors fitted to a device. A nil set means the
+// model was not recognized and every sensor is assumed to be present.
+type capabilities map[sensor]bool
+
+// parseModel decodes a device model string into the set of fitted sensors.
+func parseModel(model string) capabilities {
+	model = strings.ToUpper(strings.TrimSpace(model))
+	for name, sensors := range knownModels {
+		if model == name || strings.HasPrefix(model, name+"-") {
+			return newCapabilities(sensors...)
+		}
+	}
+
+	match := openAirModel.FindStringSubmatch(model)
+	if match == nil {
+		return nil
+	}
+	var sensors []sensor
+	for _, letter := range match[1] {
+		switch letter {
+		case 'P':
+			// Open Air boards use the PMS5003T, which also reports temperature
+			// and humidity.
+			sensors = append(sensors, sensorPMS5003T)
+		case 'S':
+			sensors = append(sensors, sensorS8)
+		case 'T':
+			sensors = append(sensors, sensorSGP41)
+		}
+	}
+	return newCapabilities(sensors...)
+}
+
+// environmentFromModel infers whether a device is installed indoor or outdoor
+// from its model. It returns an empty string if the model is not recognized.
+func environmentFromModel(model string) string {
+	model = strings.ToUpper(strings.TrimSpace(model))
+	switch {
+	case strings.HasPrefix(model, "I-"), strings.HasPrefix(model, "DIY-"):
+		return config.EnvironmentIndoor
+	case strings.HasPrefix(model, "O-"):
+		return config.EnvironmentOutdoor
+	}
+	return ""
+}
+
+func newCapabilities(sensors ...sensor) capabilities {
+	c := make(capabilities, len(sensors))
+	for _, s := range sensors {
+		c[s] = true
+	}
+	return c
+}
+
+// supports reports whether any of the provided sensors are fitted. An empty
+// list of sensors is always supported.
+func (c capabilities) supports(sensors ...sensor) bool {
+	if c == nil || len(sensors) == 0 {
+		return true
+	}
+	for _, s := range sensors {
+		if c[s] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/mold"
+	"github.com/dtrejod/airgradient-exporter/internal/psychro"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const moldGroup = "mold"
+
+// moldFamilies exports the mold and condensation risk of a device's room.
+type moldFamilies struct {
+	index        *family
+	favourable   *family
+	condensation *family
+	margin       *family
+}
+
+func (c *airgradientCollector) newMoldFamilies() *moldFamilies {
+	return &moldFamilies{
+		index:        c.newFamily("mold_index", moldGroup, "VTT mold index from 0 for no growth to 6 for heavy growth", prometheus.GaugeValue, temperatureSensors, nil),
+		favourable:   c.newFamily("mold_growth_favourable", moldGroup, "Whether the temperature and humidity allow mold growth", prometheus.GaugeValue, temperatureSensors, nil),
+		condensation: c.newFamily("condensation_risk", moldGroup, "Whether the dew point is within the configured margin of the surface temperature", prometheus.GaugeValue, temperatureSensors, nil),
+		margin:       c.newFamily("surface_dew_point_margin", moldGroup, "Surface temperature above the dew point in Degrees Celsius", prometheus.GaugeValue, temperatureSensors, nil),
+	}
+}
+
+func (f *moldFamilies) families() []*family {
+	return []*family{f.index, f.favourable, f.condensation, f.margin}
+}
+
+// moldIndex advances the device's mold growth model with the readings taken since
+// it was last updated and returns its index.
+func (c *airgradientCollector) moldIndex(d *device) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c.replay(d, &d.moldUpdated, func(s sample, held time.Duration) {
+		d.moldModel.Step(s.m.ATMP, float64(s.m.RHUM), held)
+	})
+	return d.moldModel.Index
+}
+
+// collectMold emits the mold index and condensation risk of the scraped device's
+// room, if configured.
+func (c *airgradientCollector) collectMold(ch chan<- prometheus.Metric, s *scrape) {
+	cfg := s.device.mold
+	if cfg == nil || !s.caps.supports(temperatureSensors...) {
+		return
+	}
+	t, rh := s.measures.ATMP, float64(s.measures.RHUM)
+
+	c.emit(ch, s, c.mold.index, c.moldIndex(s.device))
+	c.emit(ch, s, c.mold.favourable, boolValue(mold.Favourable(t, rh)))
+
+	if cfg.SurfaceTemperature == nil || rh <= 0 {
+		return
+	}
+	margin := *cfg.SurfaceTemperature - psychro.DewPoint(t, rh)
+	c.emit(ch, s, c.mold.margin, margin)
+	c.emit(ch, s, c.mold.condensation, boolValue(margin <= *cfg.CondensationMargin))
+}
+
+// boolValue converts a boolean to a gauge value.
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
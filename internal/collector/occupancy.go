@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/occupancy"
+	"github.com/dtrejod/airgradient-exporter/internal/stats"
+	"github.com/dtrejod/airgradient-exporter/internal/ventilation"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	occupancyGroup = "occupancy"
+	// occupancyWindow is the history the rate of change of CO2 is fitted to.
+	occupancyWindow = 10 * time.Minute
+	// flowRateUncertainty is the relative uncertainty of the ventilation flow
+	// rate.
+	flowRateUncertainty = 0.2
+)
+
+// s8Uncertainty returns the accuracy of the SenseAir S8 at a CO2 concentration,
+// ±40 ppm ±3% of the reading.
+func s8Uncertainty(co2 float64) float64 {
+	return 40 + 0.03*co2
+}
+
+// occupancyFamilies exports the estimated number of occupants of a device's
+// room.
+type occupancyFamilies struct {
+	estimate *family
+	lower    *family
+	upper    *family
+}
+
+func (c *airgradientCollector) newOccupancyFamilies() *occupancyFamilies {
+	return &occupancyFamilies{
+		estimate: c.newFamily("occupancy_estimate", occupancyGroup, "Number of occupants estimated from the CO2 mass balance", prometheus.GaugeValue, co2Sensors, nil,
+			"model"),
+		lower: c.newFamily("occupancy_estimate_lower", occupancyGroup, "Lower bound of the 95% confidence interval of the number of occupants", prometheus.GaugeValue, co2Sensors, nil,
+			"model"),
+		upper: c.newFamily("occupancy_estimate_upper", occupancyGroup, "Upper bound of the 95% confidence interval of the number of occupants", prometheus.GaugeValue, co2Sensors, nil,
+			"model"),
+	}
+}
+
+func (f *occupancyFamilies) families() []*family {
+	return []*family{f.estimate, f.lower, f.upper}
+}
+
+// flowRate returns the ventilation flow rate of the device's room in L/s, as
+// configured or otherwise estimated from CO2 decays.
+func (d *device) flowRate() (float64, bool) {
+	cfg := d.ventilation
+	if cfg == nil {
+		return 0, false
+	}
+	if cfg.FlowRate > 0 {
+		return cfg.FlowRate, true
+	}
+	decay := d.lastDecay()
+	if decay == nil || cfg.Volume == 0 {
+		return 0, false
+	}
+	return ventilation.FlowRate(decay.ACH, cfg.Volume), true
+}
+
+// occupancyInputs returns the inputs of the mass balance of the scraped device's
+// room, if occupancy estimates are configured and the flow rate is known.
+func (c *airgradientCollector) occupancyInputs(s *scrape) (occupancy.Inputs, bool) {
+	cfg := s.device.occupancy
+	if cfg == nil || s.device.environment() == config.EnvironmentOutdoor || !s.caps.supports(co2Sensors...) {
+		return occupancy.Inputs{}, false
+	}
+	flowRate, ok := s.device.flowRate()
+	if !ok {
+		return occupancy.Inputs{}, false
+	}
+
+	co2 := float64(s.measures.RCO2)
+	return occupancy.Inputs{
+		CO2:                 co2,
+		CO2Uncertainty:      s8Uncertainty(co2),
+		OutdoorCO2:          c.outdoorCO2(s.device, s.t),
+		Volume:              s.device.ventilation.Volume,
+		FlowRate:            flowRate,
+		FlowRateUncertainty: flowRateUncertainty,
+		Generation:          cfg.CO2Generation,
+	}, true
+}
+
+// collectOccupancy emits the number of occupants of the scraped device's room,
+// if configured, using the steady-state and transient mass balance models.
+func (c *airgradientCollector) collectOccupancy(ch chan<- prometheus.Metric, s *scrape) {
+	in, ok := c.occupancyInputs(s)
+	if !ok {
+		return
+	}
+	c.emitOccupancy(ch, s, "steady_state", occupancy.SteadyState(in))
+
+	var seconds, values []float64
+	for _, r := range s.device.history.since(s.t.Add(-occupancyWindow)) {
+		seconds = append(seconds, r.t.Sub(s.t).Seconds())
+		values = append(values, float64(r.m.RCO2))
+	}
+	f, err := stats.LinearRegression(seconds, values)
+	if err != nil || f.N < 3 {
+		return
+	}
+	in.Rate, in.RateUncertainty = f.Slope, f.SlopeStdErr
+	c.emitOccupancy(ch, s, "transient", occupancy.Transient(in))
+}
+
+func (c *airgradientCollector) emitOccupancy(ch chan<- prometheus.Metric, s *scrape, model string, e occupancy.Estimate) {
+	c.emit(ch, s, c.occupancy.estimate, e.Occupants, model)
+	c.emit(ch, s, c.occupancy.lower, e.Lower, model)
+	c.emit(ch, s, c.occupancy.upper, e.Upper, model)
+}
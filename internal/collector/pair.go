@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/ilog"
+	"github.com/dtrejod/airgradient-exporter/internal/stats"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	pairGroup = "pair"
+	// minInfiltrationSamples is the number of paired observations required
+	// before an infiltration factor is estimated.
+	minInfiltrationSamples = 10
+)
+
+// pairRatioFamilies lists the measure families compared between the indoor and
+// outdoor device of a pair.
+var pairRatioFamilies = []string{"pm02", "pm10", "pm003_count"}
+
+// pairTolerance is the maximum time between an indoor and outdoor reading for
+// them to be paired.
+const pairTolerance = 30 * time.Second
+
+// pair tracks an indoor device and the outdoor device measuring the air outside
+// of it.
+type pair struct {
+	indoor  string
+	outdoor string
+	window  time.Duration
+}
+
+// infiltration estimates the fraction of outdoor PM2.5 that penetrates indoors
+// as the slope of indoor over outdoor concentrations within the window.
+func (p *pair) infiltration(pm *family, in, out *scrape) (stats.Fit, error) {
+	since := in.t.Add(-p.window)
+	pairs := alignSamples(in.device.history.since(since), out.device.history.since(since), pairTolerance)
+	if len(pairs) < minInfiltrationSamples {
+		return stats.Fit{N: len(pairs)}, stats.ErrInsufficientData
+	}
+	xs := make([]float64, len(pairs))
+	ys := make([]float64, len(pairs))
+	for i, pr := range pairs {
+		xs[i], ys[i] = pm.value(pr[1]), pm.value(pr[0])
+	}
+	f, err := stats.LinearRegression(xs, ys)
+	if err != nil {
+		return stats.Fit{N: len(pairs)}, err
+	}
+	return f, nil
+}
+
+// pairs derives indoor/outdoor comparisons for the configured device pairs.
+type pairs struct {
+	pairs []*pair
+
+	pm02         *family
+	ratio        *family
+	ratioSources []*family
+	tempDelta    *family
+	tempSource   *family
+	infiltration *family
+	samples      *family
+}
+
+func (c *airgradientCollector) newPairs(cfg []config.Pair) *pairs {
+	p := &pairs{
+		ratio: c.newSharedFamily("pair_ratio", pairGroup, "Ratio of the indoor to the outdoor reading of a device pair",
+			"indoor", "outdoor", "measure"),
+		tempDelta: c.newSharedFamily("pair_temperature_delta", pairGroup, "Indoor minus outdoor temperature of a device pair in Degrees Celsius",
+			"indoor", "outdoor"),
+		infiltration: c.newSharedFamily("pair_infiltration_factor", pairGroup, "Estimated fraction of outdoor PM2.5 infiltrating indoors over the pair window",
+			"indoor", "outdoor"),
+		samples: c.newSharedFamily("pair_infiltration_samples", pairGroup, "Number of paired PM2.5 observations in the pair window",
+			"indoor", "outdoor"),
+	}
+	for _, pc := range cfg {
+		p.pairs = append(p.pairs, &pair{
+			indoor:  strings.ToLower(pc.Indoor),
+			outdoor: strings.ToLower(pc.Outdoor),
+			window:  pc.Window,
+		})
+	}
+	for _, f := range c.measureFamilies {
+		switch f.name {
+		case "pm02":
+			p.pm02 = f
+		case "atmp":
+			p.tempSource = f
+		}
+		for _, name := range pairRatioFamilies {
+			if f.name == name {
+				p.ratioSources = append(p.ratioSources, f)
+			}
+		}
+	}
+	return p
+}
+
+func (p *pairs) families() []*family {
+	return []*family{p.ratio, p.tempDelta, p.infiltration, p.samples}
+}
+
+// collectPairs emits the comparisons of every pair whose devices were both
+// scraped, for the measures both devices export.
+func (c *airgradientCollector) collectPairs(ch chan<- prometheus.Metric, scrapes []*scrape, sel selector) {
+	bySerial := make(map[string]*scrape, len(scrapes))
+	for _, s := range scrapes {
+		if s != nil {
+			bySerial[strings.ToLower(s.measures.SerialNo)] = s
+		}
+	}
+
+	for _, p := range c.pairs.pairs {
+		in, out := bySerial[p.indoor], bySerial[p.outdoor]
+		if in == nil || out == nil {
+			ilog.FromContext(c.ctx).Debug("Skipping device pair with missing device.",
+				zap.String("indoor", p.indoor), zap.String("outdoor", p.outdoor))
+			continue
+		}
+		indoor, outdoor := c.relabeler.serial(in.measures.SerialNo), c.relabeler.serial(out.measures.SerialNo)
+
+		for _, f := range c.pairs.ratioSources {
+			if !in.exports(f) || !out.exports(f) {
+				continue
+			}
+			if o := f.value(out.measures); o > 0 {
+				c.emitShared(ch, sel, c.pairs.ratio, f.value(in.measures)/o, indoor, outdoor, f.name)
+			}
+		}
+
+		t := c.pairs.tempSource
+		if in.exports(t) && out.exports(t) {
+			c.emitShared(ch, sel, c.pairs.tempDelta, t.value(in.measures)-t.value(out.measures), indoor, outdoor)
+		}
+
+		pm := c.pairs.pm02
+		if !in.exports(pm) || !out.exports(pm) {
+			continue
+		}
+		fit, err := p.infiltration(pm, in, out)
+		c.emitShared(ch, sel, c.pairs.samples, float64(fit.N), indoor, outdoor)
+		if err == nil {
+			c.emitShared(ch, sel, c.pairs.infiltration, fit.Slope, indoor, outdoor)
+		}
+	}
+}
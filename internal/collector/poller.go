@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/ilog"
+	"go.uber.org/zap"
+)
+
+// staleIntervals is the number of poll intervals after which the latest
+// reading of a device is no longer exported.
+const staleIntervals = 3
+
+var errNoReading = errors.New("no reading available")
+
+// run polls every device at the poll interval until the context is done. Each
+// device is polled on its own, so that a device that does not respond does not
+// delay the others.
+func (c *airgradientCollector) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, d := range c.devices {
+		wg.Add(1)
+		go func(d *device) {
+			defer wg.Done()
+			c.runDevice(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+}
+
+// runDevice polls the device at the poll interval until the context is done. A
+// poll is abandoned once the next one is due.
+func (c *airgradientCollector) runDevice(ctx context.Context, d *device) {
+	t := time.NewTicker(c.pollInterval)
+	defer t.Stop()
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, c.pollInterval)
+		if _, err := c.poll(pollCtx, d); err != nil {
+			ilog.FromContext(ctx).Error("Failed to get measures.", zap.Stringer("endpoint", d.endpoint), zap.Error(err))
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// poll reads the current measures of the device into its history.
+func (c *airgradientCollector) poll(ctx context.Context, d *device) (sample, error) {
+	m, err := c.getMeasures(ctx, d)
+	if err != nil {
+		return sample{}, err
+	}
+	s := sample{t: time.Now(), m: m}
+	d.setModel(m.Model)
+	d.history.add(s.t, m)
+	return s, nil
+}
+
+// read returns the reading exported for the device. Devices are polled on
+// demand unless they are polled in the background, in which case their latest
+// reading is returned if it is recent enough.
+func (c *airgradientCollector) read(d *device) (sample, error) {
+	if c.pollInterval == 0 {
+		return c.poll(c.ctx, d)
+	}
+	s, ok := d.history.latest()
+	if !ok {
+		return sample{}, errNoReading
+	}
+	if age := time.Since(s.t); age > staleIntervals*c.pollInterval {
+		return sample{}, fmt.Errorf("latest reading is stale: %s old", age.Round(time.Second))
+	}
+	return s, nil
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"github.com/dtrejod/airgradient-exporter/internal/psychro"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const psychroGroup = "psychrometric"
+
+// psychroInputs are the temperature and humidity readings psychrometric values
+// are derived from, labeled by source.
+var psychroInputs = []struct {
+	source string
+	t      func(m *measures) float64
+	rh     func(m *measures) float64
+}{
+	{"raw", func(m *measures) float64 { return m.ATMP }, func(m *measures) float64 { return float64(m.RHUM) }},
+	{"compensated", func(m *measures) float64 { return m.ATMPCompensated }, func(m *measures) float64 { return float64(m.RHUMCompensated) }},
+}
+
+// psychroFamily is a psychrometric value derived from temperature, relative
+// humidity and atmospheric pressure.
+type psychroFamily struct {
+	family *family
+	value  func(t, rh, pressure float64) float64
+}
+
+func (c *airgradientCollector) newPsychroFamilies() []psychroFamily {
+	newFamily := func(name, help string) *family {
+		return c.newFamily(name, psychroGroup, help, prometheus.GaugeValue, temperatureSensors, nil, "source")
+	}
+	return []psychroFamily{
+		{newFamily("dew_point", "Dew point in Degrees Celsius"),
+			func(t, rh, _ float64) float64 { return psychro.DewPoint(t, rh) }},
+		{newFamily("absolute_humidity", "Absolute humidity in g/m3"),
+			func(t, rh, _ float64) float64 { return psychro.AbsoluteHumidity(t, rh) }},
+		{newFamily("humidity_ratio", "Humidity ratio in grams of water vapor per kilogram of dry air"),
+			psychro.HumidityRatio},
+		{newFamily("wet_bulb", "Wet-bulb temperature in Degrees Celsius"),
+			func(t, rh, _ float64) float64 { return psychro.WetBulb(t, rh) }},
+		{newFamily("heat_index", "Heat index in Degrees Celsius"),
+			func(t, rh, _ float64) float64 { return psychro.HeatIndex(t, rh) }},
+		{newFamily("humidex", "Humidex"),
+			func(t, rh, _ float64) float64 { return psychro.Humidex(t, rh) }},
+	}
+}
+
+func psychroFamilies(families []psychroFamily) []*family {
+	fs := make([]*family, len(families))
+	for i, f := range families {
+		fs[i] = f.family
+	}
+	return fs
+}
+
+// collectPsychro emits the psychrometric values derived from the raw and
+// compensated readings of the scraped device.
+func (c *airgradientCollector) collectPsychro(ch chan<- prometheus.Metric, s *scrape) {
+	pressure := psychro.StandardPressure
+	if l := s.device.location; l != nil && l.Altitude != nil {
+		pressure = psychro.PressureAtAltitude(*l.Altitude)
+	}
+
+	for _, in := range psychroInputs {
+		t, rh := in.t(s.measures), in.rh(s.measures)
+		// Firmware without compensation reports zero humidity.
+		if rh <= 0 || rh > 100 {
+			continue
+		}
+		for _, f := range c.psychro {
+			c.emit(ch, s, f.family, f.value(t, rh, pressure), in.source)
+		}
+	}
+}
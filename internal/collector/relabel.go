@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	serialLabel = "serialno"
+	// serialHashLength is the number of hex characters kept from a serial hash.
+	serialHashLength = 12
+)
+
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// relabeler applies the configured relabel rules to the collector's output.
+type relabeler struct {
+	constantLabels prometheus.Labels
+	renames        map[string]string
+	aliases        map[string]string
+	hashSerial     bool
+	salt           string
+}
+
+func newRelabeler(cfg config.Relabel) (*relabeler, error) {
+	for name := range cfg.ConstantLabels {
+		if !labelNameRE.MatchString(name) {
+			return nil, fmt.Errorf("invalid constant label name %q", name)
+		}
+	}
+	seen := make(map[string]string)
+	for from, to := range cfg.RenameLabels {
+		if !labelNameRE.MatchString(to) {
+			return nil, fmt.Errorf("invalid label name %q for %q", to, from)
+		}
+		if other, ok := seen[to]; ok {
+			return nil, fmt.Errorf("labels %q and %q are both renamed to %q", other, from, to)
+		}
+		if _, ok := cfg.ConstantLabels[to]; ok {
+			return nil, fmt.Errorf("label %q is renamed to constant label %q", from, to)
+		}
+		seen[to] = from
+	}
+	if cfg.HashSerial && cfg.HashSalt == "" {
+		return nil, fmt.Errorf("hash_serial requires a hash_salt")
+	}
+
+	return &relabeler{
+		constantLabels: cfg.ConstantLabels,
+		renames:        cfg.RenameLabels,
+		aliases:        cfg.SerialAliases,
+		hashSerial:     cfg.HashSerial,
+		salt:           cfg.HashSalt,
+	}, nil
+}
+
+// validate returns an error if the rules make a label of a family collide with
+// another of its labels.
+func (r *relabeler) validate(families []*family) error {
+	for _, f := range families {
+		exported := make(map[string]string, len(f.labels))
+		for _, l := range f.labels {
+			to := r.rename(l)
+			if other, ok := exported[to]; ok {
+				return fmt.Errorf("%s collides with %s of %s_%s", r.describe(l), r.describe(other), namespace, f.name)
+			}
+			if _, ok := r.constantLabels[to]; ok {
+				return fmt.Errorf("constant label %q collides with %s of %s_%s", to, r.describe(l), namespace, f.name)
+			}
+			exported[to] = l
+		}
+	}
+	return nil
+}
+
+// describe names a label in errors, along with its new name if renamed.
+func (r *relabeler) describe(label string) string {
+	if to := r.rename(label); to != label {
+		return fmt.Sprintf("label %q renamed to %q", label, to)
+	}
+	return fmt.Sprintf("label %q", label)
+}
+
+// rename returns the exported name of a label.
+func (r *relabeler) rename(label string) string {
+	if to, ok := r.renames[label]; ok {
+		return to
+	}
+	return label
+}
+
+// newDesc creates a metric description with the rules applied to its label
+// names.
+func (r *relabeler) newDesc(name, help string, labels []string) *prometheus.Desc {
+	renamed := make([]string, len(labels))
+	for i, l := range labels {
+		renamed[i] = r.rename(l)
+	}
+	return prometheus.NewDesc(name, help, renamed, r.constantLabels)
+}
+
+// serial returns the value exported in place of the device serial number.
+func (r *relabeler) serial(serialNo string) string {
+	if alias, ok := r.aliases[strings.ToLower(serialNo)]; ok {
+		return alias
+	}
+	if r.hashSerial {
+		sum := sha256.Sum256([]byte(r.salt + serialNo))
+		return hex.EncodeToString(sum[:])[:serialHashLength]
+	}
+	return serialNo
+}
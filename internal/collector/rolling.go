@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const rollingGroup = "rolling"
+
+// rollingFamilies lists the measure families averaged over rolling windows.
+var rollingFamilies = []string{"pm01", "pm02", "pm10", "rco2", "tvoc_index", "nox_index"}
+
+// rolling exports time-weighted averages of device measures over rolling
+// windows.
+type rolling struct {
+	windows []time.Duration
+	sources []*family
+
+	average *family
+	samples *family
+}
+
+func (c *airgradientCollector) newRolling(windows []time.Duration) *rolling {
+	r := &rolling{
+		windows: windows,
+		average: c.newFamily("rolling_average", rollingGroup, "Time-weighted average of a measure over a rolling window", prometheus.GaugeValue, nil, nil,
+			"measure", "window"),
+		samples: c.newFamily("rolling_samples", rollingGroup, "Number of readings within a rolling window", prometheus.GaugeValue, nil, nil,
+			"window"),
+	}
+	for _, f := range c.measureFamilies {
+		for _, name := range rollingFamilies {
+			if f.name == name {
+				r.sources = append(r.sources, f)
+			}
+		}
+	}
+	return r
+}
+
+func (r *rolling) families() []*family {
+	return []*family{r.average, r.samples}
+}
+
+// collectRolling emits the rolling averages of the scraped device.
+func (c *airgradientCollector) collectRolling(ch chan<- prometheus.Metric, s *scrape) {
+	for _, w := range c.rolling.windows {
+		window := formatWindow(w)
+		samples := s.device.history.since(s.t.Add(-w))
+		c.emit(ch, s, c.rolling.samples, float64(len(samples)), window)
+		if len(samples) == 0 {
+			continue
+		}
+		for _, f := range c.rolling.sources {
+			if !s.caps.supports(f.sensors...) || s.suppressed(f) {
+				continue
+			}
+			c.emit(ch, s, c.rolling.average, timeWeightedMean(samples, s.t, c.maxHold, f.value), f.name, window)
+		}
+	}
+}
+
+// timeWeightedMean averages the readings weighted by the time each one held
+// until the next reading, or until now for the latest one. Holds are capped at
+// maxHold. A single reading is returned as is.
+func timeWeightedMean(samples []sample, now time.Time, maxHold time.Duration, value func(m *measures) float64) float64 {
+	var sum, total float64
+	for i, s := range samples {
+		end := now
+		if i+1 < len(samples) {
+			end = samples[i+1].t
+		}
+		hold := end.Sub(s.t)
+		if hold > maxHold {
+			hold = maxHold
+		}
+		sum += value(s.m) * hold.Seconds()
+		total += hold.Seconds()
+	}
+	if total == 0 {
+		return value(samples[len(samples)-1].m)
+	}
+	return sum / total
+}
+
+// formatWindow formats a window in the largest whole unit, e.g. 8h or 15m.
+func formatWindow(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"fmt"
+	"path"
+)
+
+// selector picks metric families by name or group. Patterns use shell-style
+// globbing, e.g. "*_raw" or "pm*".
+type selector struct {
+	include []string
+	exclude []string
+}
+
+// matches reports whether the family is selected. A selector with no include
+// patterns selects every family that is not excluded.
+func (s selector) matches(f *family) bool {
+	if len(s.include) > 0 && !matchAny(s.include, f) {
+		return false
+	}
+	return !matchAny(s.exclude, f)
+}
+
+func matchAny(patterns []string, f *family) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, f.name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, f.group); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePatterns returns an error if a pattern is malformed or matches none of
+// the provided families.
+func validatePatterns(patterns []string, families []*family) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid metric pattern %q: %w", p, err)
+		}
+		if !matchSome(p, families) {
+			return fmt.Errorf("metric pattern %q does not match any metric family or group", p)
+		}
+	}
+	return nil
+}
+
+func matchSome(pattern string, families []*family) bool {
+	for _, f := range families {
+		if matchAny([]string{pattern}, f) {
+			return true
+		}
+	}
+	return false
+}
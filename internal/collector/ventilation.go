@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/dtrejod/airgradient-exporter/internal/ventilation"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	ventilationGroup = "ventilation"
+	// ventilationLookback is the history searched for CO2 decays.
+	ventilationLookback = 24 * time.Hour
+	// outdoorMaxAge is the age beyond which the latest reading of an outdoor
+	// device is not used as the outdoor CO2 concentration.
+	outdoorMaxAge = 15 * time.Minute
+)
+
+// ventilationFamilies exports the ventilation of a device's room estimated from
+// CO2 decays.
+type ventilationFamilies struct {
+	ach       *family
+	r2        *family
+	flowRate  *family
+	timestamp *family
+}
+
+func (c *airgradientCollector) newVentilationFamilies() *ventilationFamilies {
+	return &ventilationFamilies{
+		ach:       c.newFamily("ventilation_ach", ventilationGroup, "Air changes per hour fitted to the latest CO2 decay", prometheus.GaugeValue, co2Sensors, nil),
+		r2:        c.newFamily("ventilation_fit_r2", ventilationGroup, "Coefficient of determination of the air change rate fit", prometheus.GaugeValue, co2Sensors, nil),
+		flowRate:  c.newFamily("ventilation_rate", ventilationGroup, "Ventilation flow rate in L/s of the configured room volume", prometheus.GaugeValue, co2Sensors, nil),
+		timestamp: c.newFamily("ventilation_decay_timestamp_seconds", ventilationGroup, "End time of the CO2 decay the air change rate was fitted to", prometheus.GaugeValue, co2Sensors, nil),
+	}
+}
+
+func (f *ventilationFamilies) families() []*family {
+	return []*family{f.ach, f.r2, f.flowRate, f.timestamp}
+}
+
+// outdoorCO2 returns the outdoor CO2 concentration the device's room is
+// ventilated with, read from the configured outdoor device while it has a recent
+// reading.
+func (c *airgradientCollector) outdoorCO2(d *device, now time.Time) float64 {
+	cfg := d.ventilation
+	if cfg == nil {
+		return config.DefaultOutdoorCO2
+	}
+	if cfg.OutdoorDevice != "" {
+		for _, o := range c.devices {
+			r, ok := o.history.latest()
+			if ok && strings.EqualFold(r.m.SerialNo, cfg.OutdoorDevice) && now.Sub(r.t) <= outdoorMaxAge && r.m.RCO2 > 0 {
+				return float64(r.m.RCO2)
+			}
+		}
+	}
+	return cfg.OutdoorCO2
+}
+
+// latestDecay returns the latest CO2 decay of the device toward the outdoor
+// concentration, searching its history for a decay more recent than the last
+// one found.
+func (d *device) latestDecay(now time.Time, outdoor float64) *ventilation.Decay {
+	var readings []ventilation.Reading
+	for _, s := range d.history.since(now.Add(-ventilationLookback)) {
+		readings = append(readings, ventilation.Reading{T: s.t, CO2: float64(s.m.RCO2)})
+	}
+	found, err := ventilation.LatestDecay(readings, outdoor)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil && (d.decay == nil || !found.End.Before(d.decay.End)) {
+		d.decay = &found
+	}
+	return d.decay
+}
+
+// lastDecay returns the latest CO2 decay found in the device history, if any.
+func (d *device) lastDecay() *ventilation.Decay {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.decay
+}
+
+// collectVentilation emits the ventilation of the scraped indoor device fitted
+// to the latest decay of its CO2 concentration.
+func (c *airgradientCollector) collectVentilation(ch chan<- prometheus.Metric, s *scrape) {
+	if s.device.environment() == config.EnvironmentOutdoor || !s.caps.supports(co2Sensors...) {
+		return
+	}
+	decay := s.device.latestDecay(s.t, c.outdoorCO2(s.device, s.t))
+	if decay == nil {
+		return
+	}
+
+	c.emit(ch, s, c.ventilation.ach, decay.ACH)
+	c.emit(ch, s, c.ventilation.r2, decay.R2)
+	c.emit(ch, s, c.ventilation.timestamp, float64(decay.End.Unix()))
+	if cfg := s.device.ventilation; cfg != nil && cfg.Volume > 0 {
+		c.emit(ch, s, c.ventilation.flowRate, ventilation.FlowRate(decay.ACH, cfg.Volume))
+	}
+}
@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// bootCycle is the period the device increments its boot counter at.
+const bootCycle = time.Minute
+
+// warmup tracks device reboots and the sensors warming up after them.
+type warmup struct {
+	// periods are the warm-up times of the sensors needing one.
+	periods map[sensor]time.Duration
+	// suppress is whether series of warming up sensors are dropped.
+	suppress bool
+
+	warming *family
+	reboots *family
+}
+
+// bootState holds the boot tracking of a device, updated with the readings up to
+// updated.
+type bootState struct {
+	updated time.Time
+	last    int
+	// at is the estimated time the device last booted.
+	at      time.Time
+	reboots float64
+}
+
+func (c *airgradientCollector) newWarmup(cfg config.Warmup) *warmup {
+	co2, voc := cfg.CO2, cfg.VOC
+	if co2 == 0 {
+		co2 = config.DefaultCO2Warmup
+	}
+	if voc == 0 {
+		voc = config.DefaultVOCWarmup
+	}
+	return &warmup{
+		periods:  map[sensor]time.Duration{sensorS8: co2, sensorSGP41: voc},
+		suppress: cfg.Suppress,
+		warming: c.newFamily("sensor_warming_up", "device", "Whether a sensor is warming up after the device booted", prometheus.GaugeValue, nil, nil,
+			"sensor"),
+		reboots: c.newFamily("device_reboots_total", "device", "Number of device reboots detected from its boot counter", prometheus.CounterValue, nil, nil),
+	}
+}
+
+func (w *warmup) families() []*family {
+	return []*family{w.warming, w.reboots}
+}
+
+// updateBoot tracks the boot counter of the device's readings taken since it was
+// last updated. A counter lower than the previous one is a reboot.
+func (c *airgradientCollector) updateBoot(d *device) bootState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := &d.boot
+	c.replay(d, &st.updated, func(s sample, held time.Duration) {
+		boot := s.m.Boot
+		if held == 0 || boot < st.last {
+			if held > 0 {
+				st.reboots++
+			}
+			st.at = s.t.Add(-time.Duration(boot) * bootCycle)
+		}
+		st.last = boot
+	})
+	return *st
+}
+
+// collectWarmup emits the reboots of the scraped device and which of its sensors
+// are warming up, marking them on the scrape if their series are suppressed.
+func (c *airgradientCollector) collectWarmup(ch chan<- prometheus.Metric, s *scrape) {
+	boot := c.updateBoot(s.device)
+	c.emit(ch, s, c.warmup.reboots, boot.reboots)
+
+	for _, sensor := range []sensor{sensorS8, sensorSGP41} {
+		if !s.caps.supports(sensor) {
+			continue
+		}
+		warming := !boot.at.IsZero() && s.t.Sub(boot.at) < c.warmup.periods[sensor]
+		if warming && c.warmup.suppress {
+			if s.warming == nil {
+				s.warming = make(capabilities)
+			}
+			s.warming[sensor] = true
+		}
+		c.emit(ch, s, c.warmup.warming, boolValue(warming), string(sensor))
+	}
+}
+
+// warmingUp reports whether the family's value in a reading is left out of the
+// state accumulated from the device history, because every fitted sensor it
+// depends on was warming up and warm-up series are suppressed.
+func (c *airgradientCollector) warmingUp(m *measures, f *family) bool {
+	if !c.warmup.suppress || len(f.sensors) == 0 {
+		return false
+	}
+	caps := parseModel(m.Model)
+	uptime := time.Duration(m.Boot) * bootCycle
+	for _, sensor := range f.sensors {
+		if caps.supports(sensor) && uptime >= c.warmup.periods[sensor] {
+			return false
+		}
+	}
+	return true
+}
+
+// suppressed reports whether the family is dropped because every fitted sensor
+// it depends on is warming up.
+func (s *scrape) suppressed(f *family) bool {
+	if len(s.warming) == 0 || len(f.sensors) == 0 {
+		return false
+	}
+	for _, sensor := range f.sensors {
+		if s.caps.supports(sensor) && !s.warming[sensor] {
+			return false
+		}
+	}
+	return true
+}
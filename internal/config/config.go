@@ -0,0 +1,542 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config is the exporter configuration file.
+type Config struct {
+	Devices []Device `mapstructure:"devices"`
+	Pairs   []Pair   `mapstructure:"pairs"`
+	Relabel Relabel  `mapstructure:"relabel"`
+	// AQIStandards lists the air quality index standards computed for devices
+	// that do not configure their own. When empty, the US EPA index and the
+	// standard matching the country configured on the device are computed.
+	AQIStandards []string `mapstructure:"aqi_standards"`
+	// PollInterval is the interval devices are polled at in the background.
+	// When zero, devices are polled when the exporter is scraped.
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// RollingWindows are the windows rolling averages are computed over,
+	// defaulting to DefaultRollingWindows.
+	RollingWindows []time.Duration `mapstructure:"rolling_windows"`
+	// Calibrations maps device serial numbers to their calibration.
+	Calibrations map[string]Calibration `mapstructure:"calibrations"`
+	// Thresholds count the time measures spend above a value, defaulting to
+	// DefaultThresholds.
+	Thresholds []Threshold `mapstructure:"thresholds"`
+	// Doses lists the measures integrated over time, defaulting to
+	// DefaultDoses.
+	Doses []string `mapstructure:"doses"`
+	// Baselines enables learning the usual values of measures per device.
+	Baselines *Baselines `mapstructure:"baselines"`
+	// SensorHealth tunes the sensor health diagnostics.
+	SensorHealth SensorHealth `mapstructure:"sensor_health"`
+	// Warmup configures the warm-up of sensors after a device reboots.
+	Warmup Warmup `mapstructure:"warmup"`
+	// DerivedMetrics are computed from the measures of every device.
+	DerivedMetrics []DerivedMetric `mapstructure:"derived_metrics"`
+}
+
+// DerivedMetric defines a metric computed from the measures of a device.
+type DerivedMetric struct {
+	// Name of the metric, exported with the airgradient_ prefix.
+	Name string `mapstructure:"name"`
+	Help string `mapstructure:"help"`
+	// Expression is an arithmetic expression over the measure families of
+	// the device, e.g. rco2 or pm02_compensated, and the derived metrics
+	// defined before it.
+	Expression string `mapstructure:"expression"`
+}
+
+// Defaults of the Warmup durations. The SGP41 VOC and NOx index algorithms
+// need about an hour to learn the baseline of the room.
+const (
+	DefaultCO2Warmup = 3 * time.Minute
+	DefaultVOCWarmup = time.Hour
+)
+
+// Warmup configures the warm-up of sensors after a device reboots.
+type Warmup struct {
+	// CO2 is the warm-up time of the CO2 sensor, defaulting to
+	// DefaultCO2Warmup.
+	CO2 time.Duration `mapstructure:"co2"`
+	// VOC is the learning time of the VOC and NOx indices, defaulting to
+	// DefaultVOCWarmup.
+	VOC time.Duration `mapstructure:"voc"`
+	// Suppress drops the series of sensors while they warm up.
+	Suppress bool `mapstructure:"suppress"`
+}
+
+// DefaultStuckReadings is the default number of identical consecutive readings
+// after which a sensor is considered stuck.
+const DefaultStuckReadings = 60
+
+// SensorHealth tunes the sensor health diagnostics.
+type SensorHealth struct {
+	// StuckReadings is the number of identical consecutive readings after
+	// which a sensor is considered stuck, defaulting to DefaultStuckReadings.
+	StuckReadings int `mapstructure:"stuck_readings"`
+}
+
+// Baselines configures learning the usual values of measures for each hour of
+// the week.
+type Baselines struct {
+	// Path is the file the baselines are persisted to.
+	Path string `mapstructure:"path"`
+	// Measures lists the measures learned, defaulting to
+	// DefaultBaselineMeasures.
+	Measures []string `mapstructure:"measures"`
+	// SaveInterval is the interval the baselines are persisted at, defaulting
+	// to DefaultBaselineSaveInterval.
+	SaveInterval time.Duration `mapstructure:"save_interval"`
+}
+
+// DefaultBaselineMeasures are the measures learned by default.
+var DefaultBaselineMeasures = []string{"pm02", "rco2"}
+
+// DefaultBaselineSaveInterval is the default interval baselines are persisted
+// at.
+const DefaultBaselineSaveInterval = 5 * time.Minute
+
+// Threshold counts the time a measure spends above a value.
+type Threshold struct {
+	// Measure is the name of the measure metric family, e.g. pm02.
+	Measure string  `mapstructure:"measure"`
+	Above   float64 `mapstructure:"above"`
+}
+
+// DefaultThresholds are the WHO 24 hour PM2.5 guideline and a common indoor CO2
+// limit.
+var DefaultThresholds = []Threshold{{Measure: "pm02", Above: 15}, {Measure: "rco2", Above: 1000}}
+
+// DefaultDoses are the measures integrated over time by default.
+var DefaultDoses = []string{"pm02"}
+
+// Calibration holds the polynomial coefficients correcting the readings of a
+// device, in ascending order of degree. For example [0.5, 0.98] calibrates a
+// reading x to 0.5 + 0.98x. Readings without coefficients are not calibrated.
+type Calibration struct {
+	// Version identifies the calibration, e.g. the date of the co-location.
+	Version     string    `mapstructure:"version"`
+	Temperature []float64 `mapstructure:"temperature"`
+	Humidity    []float64 `mapstructure:"humidity"`
+	CO2         []float64 `mapstructure:"co2"`
+	PM25        []float64 `mapstructure:"pm25"`
+}
+
+// DefaultRollingWindows are the windows of the WHO and EPA guidelines.
+var DefaultRollingWindows = []time.Duration{time.Hour, 8 * time.Hour, 24 * time.Hour}
+
+// DefaultPairWindow is the default rolling window used to estimate the
+// infiltration factor of a Pair.
+const DefaultPairWindow = time.Hour
+
+// Pair declares an indoor device and the outdoor device measuring the air
+// outside of it.
+type Pair struct {
+	// Indoor is the serial number of the indoor device.
+	Indoor string `mapstructure:"indoor"`
+	// Outdoor is the serial number of the outdoor device.
+	Outdoor string `mapstructure:"outdoor"`
+	// Window is the rolling window used to estimate the infiltration factor.
+	Window time.Duration `mapstructure:"window"`
+}
+
+// Device configures a single AirGradient device.
+type Device struct {
+	// Endpoint is the AirGradient local-server endpoint of the device.
+	Endpoint string `mapstructure:"endpoint"`
+	// Include lists the metric families or groups to export. All families are
+	// exported when empty.
+	Include []string `mapstructure:"include"`
+	// Exclude lists the metric families or groups to drop.
+	Exclude []string `mapstructure:"exclude"`
+	// Location describes where the device is installed.
+	Location *Location `mapstructure:"location"`
+	// AQIStandards lists the air quality index standards computed for the
+	// device, overriding the global standards.
+	AQIStandards []string `mapstructure:"aqi_standards"`
+	// Comfort enables thermal comfort metrics for the occupants of the room.
+	Comfort *Comfort `mapstructure:"comfort"`
+	// Ventilation describes the room for ventilation metrics.
+	Ventilation *Ventilation `mapstructure:"ventilation"`
+	// Occupancy enables occupancy estimates for the room, which requires a
+	// ventilation volume.
+	Occupancy *Occupancy `mapstructure:"occupancy"`
+	// InfectionRisk enables airborne infection risk metrics for the room.
+	InfectionRisk *InfectionRisk `mapstructure:"infection_risk"`
+	// Mold enables mold and condensation risk metrics for the room.
+	Mold *Mold `mapstructure:"mold"`
+}
+
+// DefaultOutdoorCO2 is the typical outdoor CO2 concentration in ppm.
+const DefaultOutdoorCO2 = 420
+
+// Ventilation describes the room a device is installed in for ventilation
+// metrics.
+type Ventilation struct {
+	// Volume is the volume of the room in m3.
+	Volume float64 `mapstructure:"volume"`
+	// OutdoorCO2 is the outdoor CO2 concentration in ppm, defaulting to
+	// DefaultOutdoorCO2.
+	OutdoorCO2 float64 `mapstructure:"outdoor_co2"`
+	// OutdoorDevice is the serial number of an outdoor device whose CO2
+	// concentration is used instead of OutdoorCO2 while it is available.
+	OutdoorDevice string `mapstructure:"outdoor_device"`
+	// FlowRate is the ventilation flow rate in L/s. When zero, it is estimated
+	// from CO2 decays.
+	FlowRate float64 `mapstructure:"flow_rate"`
+}
+
+func (v *Ventilation) setDefaults() {
+	if v.OutdoorCO2 == 0 {
+		v.OutdoorCO2 = DefaultOutdoorCO2
+	}
+}
+
+func (v *Ventilation) validate() error {
+	if v.Volume < 0 || v.OutdoorCO2 < 0 || v.FlowRate < 0 {
+		return fmt.Errorf("volume, outdoor_co2 and flow_rate must not be negative")
+	}
+	return nil
+}
+
+// DefaultCO2Generation is the CO2 generation rate in L/s of a sedentary adult,
+// following Persily and de Jonge (2017).
+const DefaultCO2Generation = 0.0052
+
+// Occupancy describes the occupants of a room for occupancy estimates.
+type Occupancy struct {
+	// CO2Generation is the CO2 generation rate per person in L/s, defaulting
+	// to DefaultCO2Generation.
+	CO2Generation float64 `mapstructure:"co2_generation"`
+}
+
+func (o *Occupancy) setDefaults() {
+	if o.CO2Generation == 0 {
+		o.CO2Generation = DefaultCO2Generation
+	}
+}
+
+// Defaults of the InfectionRisk parameters. The exhaled CO2 concentration is the
+// one used by Rudnick and Milton (2003).
+const (
+	DefaultExhaledCO2 = 38000
+	DefaultQuanta     = 25
+	DefaultInfectors  = 1
+	DefaultExposure   = time.Hour
+)
+
+// InfectionRisk configures the Wells-Riley airborne infection risk of a room. The
+// outdoor CO2 concentration is taken from the device's Ventilation.
+type InfectionRisk struct {
+	// ExhaledCO2 is the CO2 concentration of exhaled air in ppm.
+	ExhaledCO2 float64 `mapstructure:"exhaled_co2"`
+	// Quanta is the quanta generation rate of an infector in quanta/h.
+	Quanta float64 `mapstructure:"quanta"`
+	// Infectors is the number of infectors among the occupants.
+	Infectors float64 `mapstructure:"infectors"`
+	// Exposure is the time susceptible occupants spend in the room.
+	Exposure time.Duration `mapstructure:"exposure"`
+	// Occupants is the number of people in the room. When zero, the
+	// steady-state occupancy estimate is used, which requires Occupancy.
+	Occupants float64 `mapstructure:"occupants"`
+}
+
+func (r *InfectionRisk) setDefaults() {
+	if r.ExhaledCO2 == 0 {
+		r.ExhaledCO2 = DefaultExhaledCO2
+	}
+	if r.Quanta == 0 {
+		r.Quanta = DefaultQuanta
+	}
+	if r.Infectors == 0 {
+		r.Infectors = DefaultInfectors
+	}
+	if r.Exposure == 0 {
+		r.Exposure = DefaultExposure
+	}
+}
+
+func (r *InfectionRisk) validate() error {
+	if r.ExhaledCO2 < 0 || r.Quanta < 0 || r.Infectors < 0 || r.Exposure < 0 || r.Occupants < 0 {
+		return fmt.Errorf("exhaled_co2, quanta, infectors, exposure and occupants must not be negative")
+	}
+	return nil
+}
+
+// Defaults of the Comfort parameters, typical of sedentary office work.
+const (
+	DefaultClothing      = 0.7
+	DefaultMetabolicRate = 1.2
+	DefaultAirSpeed      = 0.1
+)
+
+// Comfort describes the occupants of a room for thermal comfort metrics. Unset
+// parameters are set to their default.
+type Comfort struct {
+	// Clothing is the clothing insulation in clo.
+	Clothing *float64 `mapstructure:"clothing"`
+	// MetabolicRate is the activity level in met.
+	MetabolicRate *float64 `mapstructure:"metabolic_rate"`
+	// AirSpeed is the relative air speed in m/s.
+	AirSpeed *float64 `mapstructure:"air_speed"`
+}
+
+func (c *Comfort) setDefaults() {
+	setDefault(&c.Clothing, DefaultClothing)
+	setDefault(&c.MetabolicRate, DefaultMetabolicRate)
+	setDefault(&c.AirSpeed, DefaultAirSpeed)
+}
+
+func (c *Comfort) validate() error {
+	if c.Clothing != nil && *c.Clothing < 0 || c.AirSpeed != nil && *c.AirSpeed < 0 {
+		return fmt.Errorf("clothing and air_speed must not be negative")
+	}
+	if c.MetabolicRate != nil && *c.MetabolicRate <= 0 {
+		return fmt.Errorf("metabolic_rate must be positive")
+	}
+	return nil
+}
+
+// setDefault sets the optional value to def if unset.
+func setDefault(v **float64, def float64) {
+	if *v == nil {
+		*v = &def
+	}
+}
+
+// DefaultCondensationMargin is the default margin in Degrees Celsius between
+// the dew point and the surface temperature below which condensation is a risk.
+const DefaultCondensationMargin = 1
+
+// Mold configures the mold and condensation risk of a room.
+type Mold struct {
+	// SurfaceTemperature is the temperature in Degrees Celsius of the coldest
+	// surface of the room, e.g. an exterior wall. The condensation risk is only
+	// computed when set.
+	SurfaceTemperature *float64 `mapstructure:"surface_temperature"`
+	// CondensationMargin is the margin in Degrees Celsius between the dew point
+	// and the surface temperature below which condensation is a risk,
+	// defaulting to DefaultCondensationMargin.
+	CondensationMargin *float64 `mapstructure:"condensation_margin"`
+}
+
+func (m *Mold) setDefaults() {
+	setDefault(&m.CondensationMargin, DefaultCondensationMargin)
+}
+
+// Environment values of a Location.
+const (
+	EnvironmentIndoor  = "indoor"
+	EnvironmentOutdoor = "outdoor"
+)
+
+// Location describes where a device is installed.
+type Location struct {
+	Site     string `mapstructure:"site"`
+	Building string `mapstructure:"building"`
+	Floor    string `mapstructure:"floor"`
+	Room     string `mapstructure:"room"`
+	// Environment is either indoor or outdoor. When empty it is inferred from
+	// the device model.
+	Environment string   `mapstructure:"environment"`
+	Latitude    *float64 `mapstructure:"latitude"`
+	Longitude   *float64 `mapstructure:"longitude"`
+	// Altitude is the height above sea level in meters.
+	Altitude *float64 `mapstructure:"altitude"`
+}
+
+func (l *Location) validate() error {
+	switch l.Environment {
+	case "", EnvironmentIndoor, EnvironmentOutdoor:
+	default:
+		return fmt.Errorf("environment must be %q or %q, got %q", EnvironmentIndoor, EnvironmentOutdoor, l.Environment)
+	}
+	if l.Latitude != nil && (*l.Latitude < -90 || *l.Latitude > 90) {
+		return fmt.Errorf("latitude %v is out of range", *l.Latitude)
+	}
+	if l.Longitude != nil && (*l.Longitude < -180 || *l.Longitude > 180) {
+		return fmt.Errorf("longitude %v is out of range", *l.Longitude)
+	}
+	return nil
+}
+
+// checkRepeated returns an error if a value is listed twice.
+func checkRepeated(kind string, values []string) error {
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if seen[v] {
+			return fmt.Errorf("%s %q is repeated", kind, v)
+		}
+		seen[v] = true
+	}
+	return nil
+}
+
+// Load reads the configuration file at path. The file format is inferred from
+// its extension.
+func Load(path string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	var c Config
+	if err := v.UnmarshalExact(&c); err != nil {
+		return nil, fmt.Errorf("could not decode config file: %w", err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// validate checks the configuration and sets the defaults of omitted device
+// and pair values.
+func (c *Config) validate() error {
+	if c.PollInterval < 0 {
+		return fmt.Errorf("poll_interval must not be negative")
+	}
+	// Windows are exported in whole seconds, so windows within a second of
+	// each other would export the same series.
+	windows := make(map[time.Duration]time.Duration, len(c.RollingWindows))
+	for _, w := range c.RollingWindows {
+		if w <= 0 {
+			return fmt.Errorf("rolling window %s must be positive", w)
+		}
+		key := w.Truncate(time.Second)
+		if other, ok := windows[key]; ok {
+			if other == w {
+				return fmt.Errorf("rolling window %s is repeated", w)
+			}
+			return fmt.Errorf("rolling windows %s and %s are exported as the same window", other, w)
+		}
+		windows[key] = w
+	}
+	thresholds := make(map[Threshold]bool, len(c.Thresholds))
+	for _, t := range c.Thresholds {
+		if thresholds[t] {
+			return fmt.Errorf("threshold %s above %v is repeated", t.Measure, t.Above)
+		}
+		thresholds[t] = true
+	}
+	if err := checkRepeated("dose", c.Doses); err != nil {
+		return err
+	}
+	if err := checkRepeated("aqi standard", c.AQIStandards); err != nil {
+		return err
+	}
+	if c.SensorHealth.StuckReadings < 0 {
+		return fmt.Errorf("sensor_health stuck_readings must not be negative")
+	}
+	for i, m := range c.DerivedMetrics {
+		if m.Name == "" || m.Expression == "" {
+			return fmt.Errorf("derived metric %d requires a name and an expression", i)
+		}
+	}
+	if c.Warmup.CO2 < 0 || c.Warmup.VOC < 0 {
+		return fmt.Errorf("warmup durations must not be negative")
+	}
+	if b := c.Baselines; b != nil {
+		if b.Path == "" {
+			return fmt.Errorf("baselines require a path")
+		}
+		if b.SaveInterval < 0 {
+			return fmt.Errorf("baselines save_interval must not be negative")
+		}
+		if b.SaveInterval == 0 {
+			b.SaveInterval = DefaultBaselineSaveInterval
+		}
+		if b.Measures == nil {
+			b.Measures = DefaultBaselineMeasures
+		}
+	}
+	for i := range c.Devices {
+		d := &c.Devices[i]
+		if d.Endpoint == "" {
+			return fmt.Errorf("device %d is missing an endpoint", i)
+		}
+		if err := checkRepeated("aqi standard", d.AQIStandards); err != nil {
+			return fmt.Errorf("invalid aqi_standards for %s: %w", d.Endpoint, err)
+		}
+		if d.Location != nil {
+			if err := d.Location.validate(); err != nil {
+				return fmt.Errorf("invalid location for %s: %w", d.Endpoint, err)
+			}
+		}
+		if d.Comfort != nil {
+			if err := d.Comfort.validate(); err != nil {
+				return fmt.Errorf("invalid comfort for %s: %w", d.Endpoint, err)
+			}
+			d.Comfort.setDefaults()
+		}
+		if d.Ventilation != nil {
+			if err := d.Ventilation.validate(); err != nil {
+				return fmt.Errorf("invalid ventilation for %s: %w", d.Endpoint, err)
+			}
+			d.Ventilation.setDefaults()
+		}
+		if d.Occupancy != nil {
+			if d.Ventilation == nil || d.Ventilation.Volume == 0 {
+				return fmt.Errorf("invalid occupancy for %s: ventilation volume is required", d.Endpoint)
+			}
+			if d.Occupancy.CO2Generation < 0 {
+				return fmt.Errorf("invalid occupancy for %s: co2_generation must not be negative", d.Endpoint)
+			}
+			d.Occupancy.setDefaults()
+		}
+		if d.InfectionRisk != nil {
+			if err := d.InfectionRisk.validate(); err != nil {
+				return fmt.Errorf("invalid infection_risk for %s: %w", d.Endpoint, err)
+			}
+			if d.InfectionRisk.Occupants == 0 && d.Occupancy == nil {
+				return fmt.Errorf("invalid infection_risk for %s: occupants or an occupancy section is required", d.Endpoint)
+			}
+			d.InfectionRisk.setDefaults()
+		}
+		if d.Mold != nil {
+			if d.Mold.CondensationMargin != nil && *d.Mold.CondensationMargin < 0 {
+				return fmt.Errorf("invalid mold for %s: condensation_margin must not be negative", d.Endpoint)
+			}
+			d.Mold.setDefaults()
+		}
+	}
+	for i := range c.Pairs {
+		p := &c.Pairs[i]
+		if p.Indoor == "" || p.Outdoor == "" {
+			return fmt.Errorf("pair %d requires an indoor and outdoor serial number", i)
+		}
+		if strings.EqualFold(p.Indoor, p.Outdoor) {
+			return fmt.Errorf("pair %d uses %s as both indoor and outdoor device", i, p.Indoor)
+		}
+		if p.Window < 0 {
+			return fmt.Errorf("pair %d has a negative window", i)
+		}
+		if p.Window == 0 {
+			p.Window = DefaultPairWindow
+		}
+	}
+	return nil
+}
+
+// Relabel configures the labels attached to every exported metric.
+type Relabel struct {
+	// ConstantLabels are added to every metric.
+	ConstantLabels map[string]string `mapstructure:"constant_labels"`
+	// RenameLabels maps exported label names to the names to use instead, e.g.
+	// serialno: device.
+	RenameLabels map[string]string `mapstructure:"rename_labels"`
+	// SerialAliases maps device serial numbers to the value exported in their
+	// place.
+	SerialAliases map[string]string `mapstructure:"serial_aliases"`
+	// HashSerial replaces serial numbers without an alias by a salted hash.
+	HashSerial bool `mapstructure:"hash_serial"`
+	// HashSalt is the salt used when hashing serial numbers.
+	HashSalt string `mapstructure:"hash_salt"`
+}
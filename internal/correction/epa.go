@@ -0,0 +1,30 @@
+// Package correction corrects sensor readings against reference instruments.
+package correction
+
+import "math"
+
+// EPAPMS5003 applies the US EPA nationwide correction for Plantower PMS5003
+// sensors to a PM2.5 reading in ug/m3 given the relative humidity in percent.
+// The slope is blended from that of the 2021 correction to a steeper one between
+// 30 and 50 ug/m3, and from there to the fit for wildfire smoke between 210 and
+// 260 ug/m3, so that the correction is continuous.
+// https://doi.org/10.5194/amt-15-3315-2022
+// https://www.epa.gov/air-sensor-toolbox/technical-approaches-sensor-data-airnow-fire-and-smoke-map
+func EPAPMS5003(pm, rh float64) float64 {
+	var c float64
+	switch {
+	case pm < 30:
+		c = 0.524*pm - 0.0862*rh + 5.75
+	case pm < 50:
+		w := pm/20 - 3.0/2
+		c = (0.786*w+0.524*(1-w))*pm - 0.0862*rh + 5.75
+	case pm < 210:
+		c = 0.786*pm - 0.0862*rh + 5.75
+	case pm < 260:
+		w := pm/50 - 21.0/5
+		c = (0.69*w+0.786*(1-w))*pm - 0.0862*rh*(1-w) + 2.966*w + 5.75*(1-w) + 8.84e-4*pm*pm*w
+	default:
+		c = 2.966 + 0.69*pm + 8.84e-4*pm*pm
+	}
+	return math.Max(c, 0)
+}
@@ -0,0 +1,47 @@
+package correction
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEPAPMS5003(t *testing.T) {
+	tests := []struct {
+		pm, rh float64
+		want   float64
+	}{
+		// 0.524 PM - 0.0862 RH + 5.75 (Barkjohn et al. 2021).
+		{0, 50, 1.44},
+		{10, 40, 7.542},
+		{20, 50, 11.92},
+		// Halfway through the first blend, the slope is 0.655.
+		{40, 50, 27.64},
+		// 0.786 PM - 0.0862 RH + 5.75.
+		{100, 50, 80.04},
+		{200, 30, 160.364},
+		// Halfway through the second blend.
+		{235, 50, 0.738*235 - 0.0862*50*0.5 + 2.966*0.5 + 5.75*0.5 + 8.84e-4*235*235*0.5},
+		// 2.966 + 0.69 PM + 8.84e-4 PM² for smoke (Barkjohn et al. 2022).
+		{300, 50, 289.526},
+		{500, 20, 568.966},
+		// Negative concentrations are clipped.
+		{0, 100, 0},
+	}
+	for _, tt := range tests {
+		if got := EPAPMS5003(tt.pm, tt.rh); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EPAPMS5003(%v, %v) = %v, want %v", tt.pm, tt.rh, got, tt.want)
+		}
+	}
+}
+
+func TestEPAPMS5003Continuous(t *testing.T) {
+	const eps = 1e-9
+	for _, pm := range []float64{30, 50, 210, 260} {
+		for _, rh := range []float64{10, 50, 90} {
+			below, above := EPAPMS5003(pm-eps, rh), EPAPMS5003(pm, rh)
+			if math.Abs(above-below) > 1e-6 {
+				t.Errorf("EPAPMS5003 is discontinuous at %v ug/m3 and %v%%: %v below, %v above", pm, rh, below, above)
+			}
+		}
+	}
+}
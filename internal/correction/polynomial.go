@@ -0,0 +1,14 @@
+package correction
+
+// Polynomial is a calibration polynomial with coefficients in ascending order of
+// degree.
+type Polynomial []float64
+
+// Apply evaluates the polynomial at x.
+func (p Polynomial) Apply(x float64) float64 {
+	var y float64
+	for i := len(p) - 1; i >= 0; i-- {
+		y = y*x + p[i]
+	}
+	return y
+}
@@ -0,0 +1,361 @@
+// Package expr parses and evaluates arithmetic expressions over named values.
+//
+// Expressions support numbers, identifiers, parentheses, the binary operators
+// + - * / and ^ (power, right associative), unary minus and the functions listed
+// in Functions.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// function is a function callable from expressions.
+type function struct {
+	minArgs int
+	// maxArgs is the maximum number of arguments, or -1 if unlimited.
+	maxArgs int
+	call    func(args []float64) float64
+}
+
+var functions = map[string]function{
+	"abs":  {1, 1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt": {1, 1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"exp":  {1, 1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"log":  {1, 1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"min": {2, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	}},
+	"max": {2, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	}},
+	"clamp": {3, 3, func(a []float64) float64 { return math.Min(math.Max(a[0], a[1]), a[2]) }},
+}
+
+// Functions returns the names of the functions callable from expressions.
+func Functions() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	root   node
+	idents []string
+}
+
+// Parse parses an expression whose identifiers must be among idents.
+func Parse(src string, idents []string) (*Expr, error) {
+	p := &parser{src: src, known: idents}
+	p.next()
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.unexpected()
+	}
+	return &Expr{root: root, idents: p.idents}, nil
+}
+
+// Identifiers returns the identifiers referenced by the expression, in order of
+// first appearance.
+func (e *Expr) Identifiers() []string {
+	return append([]string(nil), e.idents...)
+}
+
+// Eval evaluates the expression, resolving identifiers with lookup.
+func (e *Expr) Eval(lookup func(ident string) float64) float64 {
+	return e.root.eval(lookup)
+}
+
+type node interface {
+	eval(lookup func(string) float64) float64
+}
+
+type number float64
+
+func (n number) eval(func(string) float64) float64 { return float64(n) }
+
+type ident string
+
+func (i ident) eval(lookup func(string) float64) float64 { return lookup(string(i)) }
+
+type negate struct{ x node }
+
+func (n negate) eval(lookup func(string) float64) float64 { return -n.x.eval(lookup) }
+
+type binary struct {
+	op   byte
+	l, r node
+}
+
+func (b binary) eval(lookup func(string) float64) float64 {
+	l, r := b.l.eval(lookup), b.r.eval(lookup)
+	switch b.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	case '/':
+		return l / r
+	}
+	return math.Pow(l, r)
+}
+
+type call struct {
+	fn   function
+	args []node
+}
+
+func (c call) eval(lookup func(string) float64) float64 {
+	args := make([]float64, len(c.args))
+	for i, a := range c.args {
+		args[i] = a.eval(lookup)
+	}
+	return c.fn.call(args)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src    string
+	pos    int
+	tok    token
+	known  []string
+	idents []string
+}
+
+// next scans the next token.
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		// Exponent, e.g. 1e-3.
+		if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			p.pos++
+			if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+				p.pos++
+			}
+			for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+				p.pos++
+			}
+		}
+		p.tok = token{kind: tokNumber, text: p.src[start:p.pos], pos: start}
+	case isLetter(c):
+		for p.pos < len(p.src) && (isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos], pos: start}
+	default:
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(c), pos: start}
+	}
+}
+
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at position %d", p.tok.text, p.tok.pos+1)
+}
+
+func (p *parser) isOp(ops string) bool {
+	return p.tok.kind == tokOp && strings.Contains(ops, p.tok.text)
+}
+
+// parseExpr parses a sum of terms.
+func (p *parser) parseExpr() (node, error) {
+	l, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		op := p.tok.text[0]
+		p.next()
+		r, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		l = binary{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+// parseTerm parses a product of factors.
+func (p *parser) parseTerm() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/") {
+		op := p.tok.text[0]
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = binary{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+// parseUnary parses a negation or a power.
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("-") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negate{x: x}, nil
+	}
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("^") {
+		return base, nil
+	}
+	p.next()
+	exp, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return binary{op: '^', l: base, r: exp}, nil
+}
+
+// parsePrimary parses a number, identifier, function call or parenthesized
+// expression.
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos+1)
+		}
+		p.next()
+		return number(v), nil
+	case tok.kind == tokIdent:
+		p.next()
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		if !slices.Contains(p.known, tok.text) {
+			return nil, fmt.Errorf("unknown identifier %q at position %d, must be one of %s", tok.text, tok.pos+1, strings.Join(p.known, ", "))
+		}
+		p.addIdent(tok.text)
+		return ident(tok.text), nil
+	case p.isOp("("):
+		p.next()
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.unexpected()
+		}
+		p.next()
+		return x, nil
+	}
+	return nil, p.unexpected()
+}
+
+// parseCall parses the arguments of a call to the function named by tok.
+func (p *parser) parseCall(tok token) (node, error) {
+	fn, ok := functions[tok.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d, must be one of %s", tok.text, tok.pos+1, strings.Join(Functions(), ", "))
+	}
+	p.next()
+
+	var args []node
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if !p.isOp(",") {
+				return nil, p.unexpected()
+			}
+			p.next()
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+
+	if len(args) < fn.minArgs || fn.maxArgs >= 0 && len(args) > fn.maxArgs {
+		return nil, fmt.Errorf("function %q at position %d takes %s, got %d", tok.text, tok.pos+1, arity(fn), len(args))
+	}
+	return call{fn: fn, args: args}, nil
+}
+
+func arity(fn function) string {
+	switch {
+	case fn.maxArgs < 0:
+		return fmt.Sprintf("at least %d arguments", fn.minArgs)
+	case fn.minArgs == fn.maxArgs && fn.minArgs == 1:
+		return "1 argument"
+	case fn.minArgs == fn.maxArgs:
+		return fmt.Sprintf("%d arguments", fn.minArgs)
+	}
+	return fmt.Sprintf("%d to %d arguments", fn.minArgs, fn.maxArgs)
+}
+
+func (p *parser) addIdent(name string) {
+	for _, i := range p.idents {
+		if i == name {
+			return
+		}
+	}
+	p.idents = append(p.idents, name)
+}
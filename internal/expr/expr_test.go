@@ -0,0 +1,138 @@
+package expr
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testIdents = []string{"x", "y", "pm02_compensated"}
+
+func testLookup(ident string) float64 {
+	switch ident {
+	case "x":
+		return 3
+	case "y":
+		return 4
+	}
+	return 10
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"1", 1},
+		{"1.5e2", 150},
+		{".5", 0.5},
+		{"x", 3},
+		{"pm02_compensated", 10},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"12 / 3 / 2", 2},
+		{"2 * x ^ 2", 18},
+		// Unary minus binds looser than the power.
+		{"-2^2", -4},
+		{"(-2)^2", 4},
+		{"2^-1", 0.5},
+		{"--x", 3},
+		{"x - -y", 7},
+		// The power is right associative.
+		{"2^3^2", 512},
+		{"(2^3)^2", 64},
+		{"abs(-x)", 3},
+		{"sqrt(x * x + y * y)", 5},
+		{"exp(0)", 1},
+		{"log(1)", 0},
+		{"min(x, y)", 3},
+		{"max(x, y, 5)", 5},
+		{"min(4, 2, 3, 1)", 1},
+		{"clamp(x, 0, 2)", 2},
+		{"clamp(x, 5, 10)", 5},
+		{"clamp(x, 0, 10)", 3},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src, testIdents)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if got := e.Eval(testLookup); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%q = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEvalNotFinite(t *testing.T) {
+	for _, src := range []string{"x / 0", "log(0)"} {
+		e, err := Parse(src, testIdents)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", src, err)
+		}
+		if got := e.Eval(testLookup); !math.IsInf(got, 0) {
+			t.Errorf("%q = %v, want infinity", src, got)
+		}
+	}
+	e, err := Parse("sqrt(-x)", testIdents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Eval(testLookup); !math.IsNaN(got) {
+		t.Errorf("sqrt(-x) = %v, want NaN", got)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	e, err := Parse("max(y, x) + y * pm02_compensated - x", testIdents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"y", "x", "pm02_compensated"}
+	if got := e.Identifiers(); !slices.Equal(got, want) {
+		t.Errorf("Identifiers() = %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"", "unexpected end of expression"},
+		{"1 +", "unexpected end of expression"},
+		{"z + 1", `unknown identifier "z" at position 1, must be one of x, y, pm02_compensated`},
+		{"x + pm02Compensated", `unknown identifier "pm02Compensated" at position 5`},
+		{"rhs(x)", `unknown function "rhs" at position 1, must be one of abs, clamp, exp, log, max, min, sqrt`},
+		{"x y", `unexpected "y" at position 3`},
+		{"1 2", `unexpected "2" at position 3`},
+		{"x)", `unexpected ")" at position 2`},
+		{"(x + 1", "unexpected end of expression"},
+		{"((x)", "unexpected end of expression"},
+		{"x * (y", "unexpected end of expression"},
+		{"()", `unexpected ")" at position 2`},
+		{"x % 2", `unexpected "%" at position 3`},
+		{"1..2", `invalid number "1..2" at position 1`},
+		{"abs()", `function "abs" at position 1 takes 1 argument, got 0`},
+		{"abs(x, y)", `function "abs" at position 1 takes 1 argument, got 2`},
+		{"min(x)", `function "min" at position 1 takes at least 2 arguments, got 1`},
+		{"max()", `function "max" at position 1 takes at least 2 arguments, got 0`},
+		{"clamp(x, 0)", `function "clamp" at position 1 takes 3 arguments, got 2`},
+		{"clamp(x, 0, 1, 2)", `function "clamp" at position 1 takes 3 arguments, got 4`},
+		{"min(x,)", `unexpected ")" at position 7`},
+		{"min(x y)", `unexpected "y" at position 7`},
+		{"min(x, y", "unexpected end of expression"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src, testIdents)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want error %q", tt.src, tt.want)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %q, want %q", tt.src, err, tt.want)
+		}
+	}
+}
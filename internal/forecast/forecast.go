@@ -0,0 +1,78 @@
+// Package forecast projects time series a short time ahead.
+package forecast
+
+import (
+	"math"
+	"slices"
+
+	"github.com/dtrejod/airgradient-exporter/internal/stats"
+)
+
+// Holt is exponential smoothing with a damped trend, see Gardner and McKenzie,
+// "Forecasting trends in time series", Management Science 31 (1985).
+type Holt struct {
+	// Alpha smooths the level.
+	Alpha float64
+	// Beta smooths the trend.
+	Beta float64
+	// Phi damps the trend, so that forecasts level off with the horizon.
+	Phi float64
+	// MinValues is the number of values required to forecast.
+	MinValues int
+}
+
+// Forecast fits the model to evenly spaced values, oldest first, and returns the
+// value projected the number of steps after the last one. Missing values are NaN:
+// those between two values are interpolated linearly, so that gaps do not
+// steepen the trend, and those after the latest value count towards the
+// steps.
+func (h Holt) Forecast(values []float64, steps int) (float64, error) {
+	first := slices.IndexFunc(values, isValue)
+	if first < 0 {
+		return 0, stats.ErrInsufficientData
+	}
+	last := len(values) - 1
+	for math.IsNaN(values[last]) {
+		last--
+	}
+	steps += len(values) - 1 - last
+	values = interpolate(values[first : last+1])
+	if len(values) < max(h.MinValues, 2) {
+		return 0, stats.ErrInsufficientData
+	}
+
+	level, trend := values[1], values[1]-values[0]
+	for _, v := range values[2:] {
+		prev := level
+		level = h.Alpha*v + (1-h.Alpha)*(prev+h.Phi*trend)
+		trend = h.Beta*(level-prev) + (1-h.Beta)*h.Phi*trend
+	}
+
+	damping, phi := 0.0, 1.0
+	for i := 0; i < steps; i++ {
+		phi *= h.Phi
+		damping += phi
+	}
+	return level + damping*trend, nil
+}
+
+func isValue(v float64) bool {
+	return !math.IsNaN(v)
+}
+
+// interpolate returns a copy of the values with the NaN values between two
+// values linearly interpolated. The first and last values must not be NaN.
+func interpolate(values []float64) []float64 {
+	filled := slices.Clone(values)
+	prev := 0
+	for i := 1; i < len(filled); i++ {
+		if math.IsNaN(filled[i]) {
+			continue
+		}
+		for j := prev + 1; j < i; j++ {
+			filled[j] = filled[prev] + (filled[i]-filled[prev])*float64(j-prev)/float64(i-prev)
+		}
+		prev = i
+	}
+	return filled
+}
@@ -0,0 +1,25 @@
+// Package infection estimates the risk of airborne infection indoors from CO2
+// concentrations, following Rudnick and Milton, "Risk of indoor airborne
+// infection transmission estimated from carbon dioxide concentration", Indoor
+// Air 13 (2003).
+package infection
+
+import (
+	"math"
+	"time"
+)
+
+// RebreathedFraction returns the fraction of inhaled air that was exhaled by
+// someone else in the room, f = (C - Co) / Ca (Rudnick and Milton, Eq. 2), where
+// C is the indoor, Co the outdoor and Ca the exhaled CO2 concentration.
+func RebreathedFraction(co2, outdoor, exhaled float64) float64 {
+	return math.Max(co2-outdoor, 0) / exhaled
+}
+
+// Probability returns the Wells-Riley probability of infection of a susceptible
+// occupant, P = 1 - exp(-f I q t / n) (Rudnick and Milton, Eq. 4), where f is the
+// rebreathed fraction, I the number of infectors among the n occupants, q the
+// quanta generation rate of an infector in quanta/h and t the exposure time.
+func Probability(f, infectors, occupants, quanta float64, exposure time.Duration) float64 {
+	return 1 - math.Exp(-f*infectors*quanta*exposure.Hours()/occupants)
+}
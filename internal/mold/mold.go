@@ -0,0 +1,91 @@
+// Package mold estimates mold growth on building surfaces with a simplified
+// VTT model, following Hukka and Viitanen, "A mathematical model of mould growth
+// on wooden material", Wood Science and Technology 33 (1999), and the sensitivity
+// classes of Ojanen et al., "Mold growth modeling of building structures using
+// sensitivity classes of materials" (2010).
+//
+// The model uses the parameters of the very sensitive class, e.g. pine sapwood,
+// so that the index is a conservative estimate for most indoor surfaces.
+package mold
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// minHumidity is the lowest relative humidity in percent allowing growth.
+	minHumidity = 80
+	// Parameters of the maximum index reachable at a humidity.
+	maxA, maxB, maxC = 1, 7, 2
+	// MaxIndex is the highest mold index, heavy growth covering the surface.
+	MaxIndex = 6
+)
+
+// CriticalHumidity returns the relative humidity in percent above which mold
+// grows at a temperature in Degrees Celsius.
+func CriticalHumidity(t float64) float64 {
+	if t > 20 {
+		return minHumidity
+	}
+	return math.Max(-0.00267*t*t*t+0.160*t*t-3.13*t+100, minHumidity)
+}
+
+// Favourable reports whether the temperature and relative humidity allow mold
+// growth.
+func Favourable(t, rh float64) bool {
+	return t > 0 && t < 50 && rh >= CriticalHumidity(t)
+}
+
+// Model tracks the mold index of a surface, from 0 for no growth to MaxIndex.
+// The zero value is a clean surface.
+type Model struct {
+	// Index is the mold index.
+	Index float64
+	// unfavourable is the time conditions have not allowed growth.
+	unfavourable time.Duration
+}
+
+// Step advances the model by the duration at the temperature in Degrees
+// Celsius and relative humidity in percent.
+func (m *Model) Step(t, rh float64, d time.Duration) {
+	if !Favourable(t, rh) {
+		m.decline(d)
+		return
+	}
+	m.unfavourable = 0
+
+	crit := CriticalHumidity(t)
+	k1 := 1.0
+	if m.Index >= 1 {
+		k1 = 2
+	}
+	ratio := (crit - rh) / (crit - 100)
+	maxIndex := maxA + maxB*ratio - maxC*ratio*ratio
+	k2 := math.Max(1-math.Exp(2.3*(m.Index-maxIndex)), 0)
+
+	// Growth rate per day.
+	rate := k1 * k2 / (7 * math.Exp(-0.68*math.Log(t)-13.9*math.Log(rh)+66.02))
+	m.Index = math.Min(m.Index+rate*d.Hours()/24, MaxIndex)
+}
+
+// decline lowers the index while conditions do not allow growth. The index drops
+// during the first 6 hours, holds until 24 hours, then drops at half the rate.
+func (m *Model) decline(d time.Duration) {
+	for d > 0 {
+		var step time.Duration
+		var rate float64
+		switch {
+		case m.unfavourable < 6*time.Hour:
+			step, rate = 6*time.Hour-m.unfavourable, 0.00133
+		case m.unfavourable < 24*time.Hour:
+			step, rate = 24*time.Hour-m.unfavourable, 0
+		default:
+			step, rate = d, 0.000667
+		}
+		step = min(step, d)
+		m.Index = math.Max(m.Index-rate*step.Hours(), 0)
+		m.unfavourable += step
+		d -= step
+	}
+}
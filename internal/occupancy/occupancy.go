@@ -0,0 +1,74 @@
+// Package occupancy estimates the number of people in a room from the CO2 mass
+// balance of the room.
+//
+// With n occupants each exhaling CO2 at the rate G and a ventilation flow rate Q
+// of outdoor air, the CO2 concentration C of a room of volume V follows
+// V dC/dt = nG - Q(C - Cout). The steady-state model assumes dC/dt = 0, while the
+// transient model uses the measured rate of change.
+package occupancy
+
+import "math"
+
+// z95 is the z-score of a 95% confidence interval.
+const z95 = 1.96
+
+// Inputs of the mass balance.
+type Inputs struct {
+	// CO2 is the indoor concentration in ppm.
+	CO2 float64
+	// CO2Uncertainty is the standard uncertainty of CO2 in ppm.
+	CO2Uncertainty float64
+	// OutdoorCO2 is the outdoor concentration in ppm.
+	OutdoorCO2 float64
+	// Rate is the rate of change of CO2 in ppm/s.
+	Rate float64
+	// RateUncertainty is the standard uncertainty of Rate in ppm/s.
+	RateUncertainty float64
+	// Volume of the room in m3.
+	Volume float64
+	// FlowRate is the ventilation flow rate in L/s.
+	FlowRate float64
+	// FlowRateUncertainty is the standard uncertainty of FlowRate relative to
+	// it.
+	FlowRateUncertainty float64
+	// Generation is the CO2 generation rate per person in L/s.
+	Generation float64
+}
+
+// Estimate is an estimated number of occupants and its 95% confidence interval.
+// None of the values are negative.
+type Estimate struct {
+	Occupants float64
+	Lower     float64
+	Upper     float64
+}
+
+// SteadyState estimates the occupants assuming the concentration is at
+// equilibrium.
+func SteadyState(in Inputs) Estimate {
+	in.Rate, in.RateUncertainty = 0, 0
+	return Transient(in)
+}
+
+// Transient estimates the occupants from the concentration and its rate of
+// change.
+func Transient(in Inputs) Estimate {
+	// Convert ppm to a volume fraction and m3 to L.
+	excess := (in.CO2 - in.OutdoorCO2) * 1e-6
+	rate := in.Rate * 1e-6
+	volume := in.Volume * 1000
+
+	n := (volume*rate + in.FlowRate*excess) / in.Generation
+
+	// Propagate the uncertainties of the independent inputs.
+	sRate := volume * in.RateUncertainty * 1e-6 / in.Generation
+	sExcess := in.FlowRate * in.CO2Uncertainty * 1e-6 / in.Generation
+	sFlow := in.FlowRate * in.FlowRateUncertainty * excess / in.Generation
+	margin := z95 * math.Sqrt(sRate*sRate+sExcess*sExcess+sFlow*sFlow)
+
+	return Estimate{
+		Occupants: math.Max(n, 0),
+		Lower:     math.Max(n-margin, 0),
+		Upper:     math.Max(n+margin, 0),
+	}
+}
@@ -0,0 +1,95 @@
+// Package psychro derives psychrometric properties of moist air from its
+// temperature in Degrees Celsius and relative humidity in percent.
+package psychro
+
+import "math"
+
+const (
+	// Magnus coefficients over water for -45..60 Degrees Celsius (Sonntag, 1990).
+	magnusA = 17.62
+	magnusB = 243.12
+	// magnusC is the saturation vapor pressure at 0 Degrees Celsius in hPa.
+	magnusC = 6.112
+
+	// StandardPressure is the sea level atmospheric pressure in hPa.
+	StandardPressure = 1013.25
+	// waterAirMassRatio is the ratio of the molar masses of water and dry air.
+	waterAirMassRatio = 0.621945
+	zeroCelsius       = 273.15
+)
+
+// SaturationVaporPressure returns the saturation vapor pressure over water in
+// hPa using the Magnus formula.
+func SaturationVaporPressure(t float64) float64 {
+	return magnusC * math.Exp(magnusA*t/(magnusB+t))
+}
+
+// VaporPressure returns the partial pressure of water vapor in hPa.
+func VaporPressure(t, rh float64) float64 {
+	return rh / 100 * SaturationVaporPressure(t)
+}
+
+// DewPoint returns the dew point in Degrees Celsius using the Magnus formula.
+func DewPoint(t, rh float64) float64 {
+	g := math.Log(rh/100) + magnusA*t/(magnusB+t)
+	return magnusB * g / (magnusA - g)
+}
+
+// AbsoluteHumidity returns the mass of water vapor per volume of air in g/m3.
+func AbsoluteHumidity(t, rh float64) float64 {
+	// 216.7 is 100 Pa/hPa * 1000 g/kg divided by the specific gas constant of
+	// water vapor, 461.5 J/(kg K).
+	return 216.7 * VaporPressure(t, rh) / (zeroCelsius + t)
+}
+
+// HumidityRatio returns the mass of water vapor per mass of dry air in g/kg at
+// the provided atmospheric pressure in hPa.
+func HumidityRatio(t, rh, pressure float64) float64 {
+	pv := VaporPressure(t, rh)
+	return 1000 * waterAirMassRatio * pv / (pressure - pv)
+}
+
+// PressureAtAltitude returns the standard atmospheric pressure in hPa at an
+// altitude in meters above sea level.
+func PressureAtAltitude(altitude float64) float64 {
+	return StandardPressure * math.Pow(1-2.25577e-5*altitude, 5.25588)
+}
+
+// WetBulb returns the wet-bulb temperature in Degrees Celsius using the
+// empirical formula by Stull (2011), valid for relative humidities between 5%
+// and 99% and temperatures between -20 and 50 Degrees Celsius.
+// https://doi.org/10.1175/JAMC-D-11-0143.1
+func WetBulb(t, rh float64) float64 {
+	return t*math.Atan(0.151977*math.Sqrt(rh+8.313659)) +
+		math.Atan(t+rh) - math.Atan(rh-1.676331) +
+		0.00391838*math.Pow(rh, 1.5)*math.Atan(0.023101*rh) -
+		4.686035
+}
+
+// HeatIndex returns the apparent temperature in Degrees Celsius using the US
+// National Weather Service algorithm.
+// https://www.wpc.ncep.noaa.gov/html/heatindex_equation.shtml
+func HeatIndex(t, rh float64) float64 {
+	f := t*9/5 + 32
+	hi := 0.5 * (f + 61 + (f-68)*1.2 + rh*0.094)
+	if (hi+f)/2 >= 80 {
+		hi = -42.379 + 2.04901523*f + 10.14333127*rh -
+			0.22475541*f*rh - 0.00683783*f*f - 0.05481717*rh*rh +
+			0.00122874*f*f*rh + 0.00085282*f*rh*rh - 0.00000199*f*f*rh*rh
+		switch {
+		case rh < 13 && f >= 80 && f <= 112:
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(f-95))/17)
+		case rh > 85 && f >= 80 && f <= 87:
+			hi += (rh - 85) / 10 * (87 - f) / 5
+		}
+	}
+	return (hi - 32) * 5 / 9
+}
+
+// Humidex returns the Canadian humidex computed from the dew point.
+// https://climate.weather.gc.ca/glossary_e.html#humidex
+func Humidex(t, rh float64) float64 {
+	td := DewPoint(t, rh) + zeroCelsius
+	e := 6.11 * math.Exp(5417.7530*(1/273.16-1/td))
+	return t + 0.5555*(e-10)
+}
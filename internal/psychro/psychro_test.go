@@ -0,0 +1,123 @@
+package psychro
+
+import (
+	"math"
+	"testing"
+)
+
+// fahrenheit converts a temperature in Degrees Celsius to Degrees Fahrenheit.
+func fahrenheit(t float64) float64 {
+	return t*9/5 + 32
+}
+
+// celsius converts a temperature in Degrees Fahrenheit to Degrees Celsius.
+func celsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+// rhFromDewPoint returns the relative humidity of air at temperature t with
+// the dew point td.
+func rhFromDewPoint(t, td float64) float64 {
+	return 100 * SaturationVaporPressure(td) / SaturationVaporPressure(t)
+}
+
+func TestDewPoint(t *testing.T) {
+	tests := []struct {
+		t, rh, want float64
+	}{
+		{25, 50, 13.9},
+		{20, 60, 12.0},
+		{30, 80, 26.2},
+		{20, 100, 20},
+	}
+	for _, tt := range tests {
+		if got := DewPoint(tt.t, tt.rh); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("DewPoint(%v, %v) = %.2f, want %v", tt.t, tt.rh, got, tt.want)
+		}
+	}
+}
+
+func TestAbsoluteHumidity(t *testing.T) {
+	tests := []struct {
+		t, rh, want float64
+	}{
+		{20, 100, 17.3},
+		{25, 50, 11.5},
+		{30, 100, 30.3},
+	}
+	for _, tt := range tests {
+		if got := AbsoluteHumidity(tt.t, tt.rh); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("AbsoluteHumidity(%v, %v) = %.2f, want %v", tt.t, tt.rh, got, tt.want)
+		}
+	}
+}
+
+func TestHumidityRatio(t *testing.T) {
+	// Values read from the ASHRAE psychrometric chart at sea level.
+	tests := []struct {
+		t, rh, want float64
+	}{
+		{25, 50, 9.9},
+		{20, 50, 7.3},
+		{30, 80, 21.5},
+	}
+	for _, tt := range tests {
+		if got := HumidityRatio(tt.t, tt.rh, StandardPressure); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("HumidityRatio(%v, %v) = %.2f, want %v", tt.t, tt.rh, got, tt.want)
+		}
+	}
+}
+
+func TestPressureAtAltitude(t *testing.T) {
+	if got := PressureAtAltitude(0); got != StandardPressure {
+		t.Errorf("PressureAtAltitude(0) = %v, want %v", got, StandardPressure)
+	}
+	// International Standard Atmosphere at 1500 m.
+	if got := PressureAtAltitude(1500); math.Abs(got-845.6) > 0.5 {
+		t.Errorf("PressureAtAltitude(1500) = %.1f, want 845.6", got)
+	}
+}
+
+func TestWetBulb(t *testing.T) {
+	// The example given by Stull (2011).
+	if got := WetBulb(20, 50); math.Abs(got-13.7) > 0.05 {
+		t.Errorf("WetBulb(20, 50) = %.2f, want 13.7", got)
+	}
+}
+
+func TestHeatIndex(t *testing.T) {
+	// Values of the NWS heat index table in Degrees Fahrenheit.
+	tests := []struct {
+		f, rh, want float64
+	}{
+		{80, 40, 80},
+		{84, 75, 92},
+		{86, 90, 105},
+		{90, 60, 100},
+		{96, 55, 112},
+		{100, 40, 109},
+		{110, 40, 136},
+	}
+	for _, tt := range tests {
+		if got := fahrenheit(HeatIndex(celsius(tt.f), tt.rh)); math.Abs(got-tt.want) > 0.5 {
+			t.Errorf("HeatIndex(%v F, %v) = %.1f F, want %v F", tt.f, tt.rh, got, tt.want)
+		}
+	}
+}
+
+func TestHumidex(t *testing.T) {
+	// Values of the Environment and Climate Change Canada humidex table, by
+	// temperature and dew point.
+	tests := []struct {
+		t, td, want float64
+	}{
+		{25, 20, 33},
+		{30, 15, 34},
+		{30, 25, 42},
+	}
+	for _, tt := range tests {
+		if got := Humidex(tt.t, rhFromDewPoint(tt.t, tt.td)); math.Abs(got-tt.want) > 0.5 {
+			t.Errorf("Humidex(%v, dew point %v) = %.1f, want %v", tt.t, tt.td, got, tt.want)
+		}
+	}
+}
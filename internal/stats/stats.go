@@ -0,0 +1,69 @@
+package stats
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInsufficientData is returned when there are too few or too uniform
+// samples to compute a statistic.
+var ErrInsufficientData = errors.New("insufficient data")
+
+// Fit is the result of a least squares linear regression y = Slope*x + Intercept.
+type Fit struct {
+	Slope     float64
+	Intercept float64
+	// R2 is the coefficient of determination of the fit.
+	R2 float64
+	// SlopeStdErr is the standard error of the slope, or zero with two samples.
+	SlopeStdErr float64
+	// N is the number of samples used for the fit.
+	N int
+}
+
+// LinearRegression fits a line through the samples using ordinary least squares.
+func LinearRegression(xs, ys []float64) (Fit, error) {
+	n := len(xs)
+	if n != len(ys) {
+		return Fit{}, errors.New("mismatched sample lengths")
+	}
+	if n < 2 {
+		return Fit{}, ErrInsufficientData
+	}
+
+	mx, my := Mean(xs), Mean(ys)
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return Fit{}, ErrInsufficientData
+	}
+
+	f := Fit{Slope: sxy / sxx, N: n}
+	f.Intercept = my - f.Slope*mx
+	f.R2 = 1
+	if syy != 0 {
+		f.R2 = (sxy * sxy) / (sxx * syy)
+	}
+	if n > 2 {
+		// The residual sum of squares is syy - slope*sxy.
+		f.SlopeStdErr = math.Sqrt(math.Max(syy-f.Slope*sxy, 0) / float64(n-2) / sxx)
+	}
+	return f, nil
+}
+
+// Mean returns the arithmetic mean of the values, or NaN if there are none.
+func Mean(vs []float64) float64 {
+	if len(vs) == 0 {
+		return math.NaN()
+	}
+	var sum float64
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs))
+}
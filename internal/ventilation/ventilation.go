@@ -0,0 +1,157 @@
+// Package ventilation estimates the ventilation of a room from the decay of its
+// CO2 concentration after occupants leave.
+//
+// Without CO2 sources, the excess concentration over the outdoor concentration
+// decays exponentially, C(t) - Cout = (C0 - Cout) exp(-λt), where λ is the air
+// change rate. λ is fitted as the slope of ln(C - Cout) over time.
+package ventilation
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/dtrejod/airgradient-exporter/internal/stats"
+)
+
+const (
+	// bucket is the period readings are averaged over to detect decays despite
+	// sensor noise.
+	bucket = 5 * time.Minute
+	// tolerance is the increase in ppm between buckets still considered part of
+	// a decay.
+	tolerance = 10
+	// minDuration is the minimum duration of a decay.
+	minDuration = 30 * time.Minute
+	// minStartExcess is the minimum excess concentration in ppm at the start
+	// of a decay.
+	minStartExcess = 200
+	// maxEndRatio is the maximum ratio between the excess concentration at
+	// the end and at the start of a decay.
+	maxEndRatio = 0.7
+	// minExcess is the excess concentration in ppm below which readings are too
+	// close to the outdoor concentration to be fitted.
+	minExcess = 50
+	// minReadings is the minimum number of readings fitted.
+	minReadings = 5
+)
+
+// ErrNoDecay is returned when the readings contain no decay.
+var ErrNoDecay = errors.New("no co2 decay found")
+
+// Reading is a CO2 concentration in ppm at a point in time.
+type Reading struct {
+	T   time.Time
+	CO2 float64
+}
+
+// Decay is the air change rate fitted to a decay of the CO2 concentration.
+type Decay struct {
+	Start time.Time
+	End   time.Time
+	// ACH is the air change rate in air changes per hour.
+	ACH float64
+	// R2 is the coefficient of determination of the fit.
+	R2 float64
+	// N is the number of readings fitted.
+	N int
+}
+
+// FlowRate converts an air change rate to the ventilation flow rate in L/s of a
+// room of the volume in m3.
+func FlowRate(ach, volume float64) float64 {
+	return ach * volume * 1000 / 3600
+}
+
+// LatestDecay finds the most recent decay of the readings toward the outdoor
+// concentration and fits its air change rate. Readings must be ordered oldest
+// first.
+func LatestDecay(readings []Reading, outdoor float64) (Decay, error) {
+	if len(readings) == 0 {
+		return Decay{}, ErrNoDecay
+	}
+
+	means := bucketMeans(readings)
+	start := readings[0].T
+	for end := len(means) - 1; end > 0; {
+		// Walk back from the end of a decay while the concentration keeps
+		// increasing toward its start.
+		i := end
+		for i > 0 && !math.IsNaN(means[i-1]) && !math.IsNaN(means[i]) && means[i-1] >= means[i]-tolerance {
+			i--
+		}
+		// The decay starts at the highest concentration, after any plateau.
+		for j := i + 1; j <= end; j++ {
+			if means[j] > means[i] {
+				i = j
+			}
+		}
+		if i < end && isDecay(means[i:end+1], outdoor) {
+			from := start.Add(time.Duration(i) * bucket)
+			to := start.Add(time.Duration(end+1) * bucket)
+			if d, err := fit(readings, from, to, outdoor); err == nil {
+				return d, nil
+			}
+		}
+		end = i - 1
+	}
+	return Decay{}, ErrNoDecay
+}
+
+// isDecay reports whether the bucket means of a decreasing run are a decay.
+func isDecay(means []float64, outdoor float64) bool {
+	if time.Duration(len(means)-1)*bucket < minDuration {
+		return false
+	}
+	first, last := means[0]-outdoor, means[len(means)-1]-outdoor
+	return first >= minStartExcess && last <= first*maxEndRatio
+}
+
+// fit fits the air change rate to the readings taken between from and to.
+func fit(readings []Reading, from, to time.Time, outdoor float64) (Decay, error) {
+	var hours, logs []float64
+	for _, r := range readings {
+		if r.T.Before(from) || !r.T.Before(to) {
+			continue
+		}
+		excess := r.CO2 - outdoor
+		if excess < minExcess {
+			continue
+		}
+		hours = append(hours, r.T.Sub(from).Hours())
+		logs = append(logs, math.Log(excess))
+	}
+	if len(hours) < minReadings {
+		return Decay{}, stats.ErrInsufficientData
+	}
+	f, err := stats.LinearRegression(hours, logs)
+	if err != nil {
+		return Decay{}, err
+	}
+	if f.Slope >= 0 {
+		return Decay{}, ErrNoDecay
+	}
+	return Decay{Start: from, End: to, ACH: -f.Slope, R2: f.R2, N: f.N}, nil
+}
+
+// bucketMeans averages the readings within consecutive buckets starting at the
+// first reading. Buckets without readings are NaN.
+func bucketMeans(readings []Reading) []float64 {
+	start := readings[0].T
+	n := int(readings[len(readings)-1].T.Sub(start)/bucket) + 1
+	sums := make([]float64, n)
+	counts := make([]int, n)
+	for _, r := range readings {
+		i := int(r.T.Sub(start) / bucket)
+		sums[i] += r.CO2
+		counts[i]++
+	}
+	for i := range sums {
+		if counts[i] == 0 {
+			sums[i] = math.NaN()
+			continue
+		}
+		sums[i] /= float64(counts[i])
+	}
+	return sums
+}